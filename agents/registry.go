@@ -0,0 +1,231 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Agent describes where an AI coding agent keeps its memory files.
+type Agent struct {
+	// ID is the short name used in the config, e.g. "claude".
+	ID string
+	// Name is the human readable name, also used as the key in
+	// aIAgentMemoryContextPath when paths are derived from the registry.
+	Name    string
+	Aliases []string
+	// Paths are the repository-relative files or directories the agent reads.
+	Paths []string
+	// IgnorePatterns are the .gitignore entries that keep Paths off the main
+	// branch; IgnorePatterns[i] covers Paths[i].
+	IgnorePatterns []string
+	// Formats are the layouts of Paths understood by the convert package;
+	// Formats[i] is the layout of Paths[i].
+	Formats []string
+	// FileTypes are hints about the file formats found under Paths.
+	FileTypes []string
+}
+
+var registry = []Agent{
+	{
+		ID:             "claude",
+		Name:           "Claude",
+		Aliases:        []string{"claude-code"},
+		Paths:          []string{"CLAUDE.md", "CLAUDE.local.md"},
+		IgnorePatterns: []string{"/CLAUDE.md", "/CLAUDE.local.md"},
+		Formats:        []string{"markdown", "markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "gemini",
+		Name:           "Gemini",
+		Aliases:        []string{"gemini-cli"},
+		Paths:          []string{"GEMINI.md"},
+		IgnorePatterns: []string{"/GEMINI.md"},
+		Formats:        []string{"markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "codex",
+		Name:           "Codex",
+		Aliases:        []string{"agents", "agents-md", "openai-codex"},
+		Paths:          []string{"AGENTS.md"},
+		IgnorePatterns: []string{"/AGENTS.md"},
+		Formats:        []string{"markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "cursor",
+		Name:           "Cursor",
+		Paths:          []string{".cursor/rules", ".cursorrules"},
+		IgnorePatterns: []string{"/.cursor/rules/", "/.cursorrules"},
+		Formats:        []string{"mdc", "markdown"},
+		FileTypes:      []string{"mdc", "md"},
+	},
+	{
+		ID:             "cline",
+		Name:           "Cline",
+		Aliases:        []string{"memory-bank"},
+		Paths:          []string{"memory-bank", ".clinerules"},
+		IgnorePatterns: []string{"/memory-bank/", "/.clinerules"},
+		Formats:        []string{"memory-bank", "markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "roo",
+		Name:           "Roo",
+		Aliases:        []string{"roo-code"},
+		Paths:          []string{".roo/rules", ".roorules"},
+		IgnorePatterns: []string{"/.roo/rules/", "/.roorules"},
+		Formats:        []string{"markdown-dir", "markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "windsurf",
+		Name:           "Windsurf",
+		Paths:          []string{".windsurf/rules", ".windsurfrules"},
+		IgnorePatterns: []string{"/.windsurf/rules/", "/.windsurfrules"},
+		Formats:        []string{"markdown-dir", "markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "copilot",
+		Name:           "Copilot",
+		Aliases:        []string{"github-copilot"},
+		Paths:          []string{".github/copilot-instructions.md", ".github/instructions"},
+		IgnorePatterns: []string{"/.github/copilot-instructions.md", "/.github/instructions/"},
+		Formats:        []string{"markdown", "markdown-dir"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "aider",
+		Name:           "Aider",
+		Paths:          []string{"CONVENTIONS.md"},
+		IgnorePatterns: []string{"/CONVENTIONS.md"},
+		Formats:        []string{"markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "continue",
+		Name:           "Continue",
+		Paths:          []string{".continue/rules"},
+		IgnorePatterns: []string{"/.continue/rules/"},
+		Formats:        []string{"markdown-dir"},
+		FileTypes:      []string{"md", "yaml"},
+	},
+	{
+		ID:             "amazonq",
+		Name:           "AmazonQ",
+		Aliases:        []string{"amazon-q", "q"},
+		Paths:          []string{".amazonq/rules"},
+		IgnorePatterns: []string{"/.amazonq/rules/"},
+		Formats:        []string{"markdown-dir"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "junie",
+		Name:           "Junie",
+		Paths:          []string{".junie/guidelines.md"},
+		IgnorePatterns: []string{"/.junie/guidelines.md"},
+		Formats:        []string{"markdown"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "kiro",
+		Name:           "Kiro",
+		Paths:          []string{".kiro/steering"},
+		IgnorePatterns: []string{"/.kiro/steering/"},
+		Formats:        []string{"markdown-dir"},
+		FileTypes:      []string{"md"},
+	},
+	{
+		ID:             "zed",
+		Name:           "Zed",
+		Paths:          []string{".rules"},
+		IgnorePatterns: []string{"/.rules"},
+		Formats:        []string{"markdown"},
+		FileTypes:      []string{"md"},
+	},
+}
+
+// All returns every known agent in registry order.
+func All() []Agent {
+	out := make([]Agent, len(registry))
+	copy(out, registry)
+	return out
+}
+
+// IDs returns the sorted IDs of all known agents.
+func IDs() []string {
+	ids := make([]string, 0, len(registry))
+	for _, a := range registry {
+		ids = append(ids, a.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Lookup finds an agent by ID, name or alias, ignoring case.
+func Lookup(name string) (Agent, bool) {
+	for _, a := range registry {
+		if strings.EqualFold(a.ID, name) || strings.EqualFold(a.Name, name) {
+			return a, true
+		}
+		for _, alias := range a.Aliases {
+			if strings.EqualFold(alias, name) {
+				return a, true
+			}
+		}
+	}
+	return Agent{}, false
+}
+
+// ContextPaths returns the aIAgentMemoryContextPath entries for the agent.
+// Agents with a single path are keyed by name; otherwise each key also
+// carries the path so that the entries stay distinct.
+func (a Agent) ContextPaths() map[string]string {
+	paths := make(map[string]string, len(a.Paths))
+	for _, p := range a.Paths {
+		paths[Detection{Agent: a, Path: p}.Key()] = p
+	}
+	return paths
+}
+
+// Detection is an agent memory path found in a repository.
+type Detection struct {
+	Agent Agent
+	Path  string
+}
+
+// Key returns the aIAgentMemoryContextPath key of the detected path, as
+// ContextPaths names it.
+func (d Detection) Key() string {
+	if len(d.Agent.Paths) > 1 {
+		return d.Agent.Name + " (" + d.Path + ")"
+	}
+	return d.Agent.Name
+}
+
+// IgnorePattern returns the registry ignore pattern covering the detected path.
+func (d Detection) IgnorePattern() string {
+	for i, p := range d.Agent.Paths {
+		if p == d.Path {
+			return d.Agent.IgnorePatterns[i]
+		}
+	}
+	return "/" + d.Path
+}
+
+// Detect returns the agents whose memory paths exist under root, in registry order.
+func Detect(root string) []Detection {
+	var found []Detection
+	for _, a := range registry {
+		for _, p := range a.Paths {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(p))); err == nil {
+				found = append(found, Detection{Agent: a, Path: p})
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,94 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	// The formats of the convert package, which imports this one.
+	formats := map[string]bool{"markdown": true, "mdc": true, "markdown-dir": true, "memory-bank": true}
+	seen := map[string]string{}
+	for _, a := range All() {
+		if a.ID == "" || a.Name == "" || len(a.Paths) == 0 {
+			t.Errorf("%q is incomplete", a.ID)
+		}
+		if len(a.IgnorePatterns) != len(a.Paths) || len(a.Formats) != len(a.Paths) {
+			t.Errorf("%s: IgnorePatterns and Formats must match Paths", a.ID)
+		}
+		for _, name := range append([]string{a.ID, a.Name}, a.Aliases...) {
+			key := strings.ToLower(name)
+			if other, ok := seen[key]; ok && other != a.ID {
+				t.Errorf("%q names both %s and %s", name, other, a.ID)
+			}
+			seen[key] = a.ID
+		}
+		for i, p := range a.Paths {
+			if !filepath.IsLocal(p) {
+				t.Errorf("%s: path %q is not relative", a.ID, p)
+			}
+			if want := "/" + p; a.IgnorePatterns[i] != want && a.IgnorePatterns[i] != want+"/" {
+				t.Errorf("%s: ignore pattern %q does not cover %q", a.ID, a.IgnorePatterns[i], p)
+			}
+			if !formats[a.Formats[i]] {
+				t.Errorf("%s: unknown format %q", a.ID, a.Formats[i])
+			}
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		ok   bool
+	}{
+		{"claude", "claude", true},
+		{"Claude", "claude", true},
+		{"CURSOR", "cursor", true},
+		{"github-copilot", "copilot", true},
+		{"amazon-q", "amazonq", true},
+		{"nobody", "", false},
+	}
+	for _, tt := range tests {
+		a, ok := Lookup(tt.name)
+		if ok != tt.ok || a.ID != tt.id {
+			t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.name, a.ID, ok, tt.id, tt.ok)
+		}
+	}
+}
+
+func TestContextPaths(t *testing.T) {
+	aider, _ := Lookup("aider")
+	if got, want := aider.ContextPaths(), map[string]string{"Aider": "CONVENTIONS.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	roo, _ := Lookup("roo")
+	want := map[string]string{"Roo (.roo/rules)": ".roo/rules", "Roo (.roorules)": ".roorules"}
+	if got := roo.ContextPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{"CLAUDE.md", ".cursor/rules/a.mdc"} {
+		path := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for _, d := range Detect(root) {
+		got = append(got, d.Agent.ID+":"+d.Path+":"+d.Key()+":"+d.IgnorePattern())
+	}
+	if want := []string{"claude:CLAUDE.md:Claude (CLAUDE.md):/CLAUDE.md", "cursor:.cursor/rules:Cursor (.cursor/rules):/.cursor/rules/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/agents"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/convert"
+	"github.com/trknhr/ai-docs/sources"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+var buildCheck bool
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Render canonical memory fragments into each agent's files",
+	Long: `Reads the memory fragments in docDir (markdown files with title, description,
+applies-to and agents frontmatter) and renders them into the file or directory
+of every configured agent, in that agent's format.
+
+Generated files start with a "do not edit" header holding a hash of their
+content. Files that were edited after they were generated, or that were not
+generated by build, are only overwritten with --force.`,
+	Args: cobra.NoArgs,
+	RunE: runBuild,
+}
+
+func init() {
+	rootCmd.AddCommand(buildCmd)
+	buildCmd.Flags().BoolVar(&buildCheck, "check", false, "only report generated files that are out of date or were edited")
+	buildCmd.Flags().BoolVar(&force, "force", false, "overwrite generated files that were edited and files not generated by build")
+}
+
+// buildTarget is the output of build for one agent path.
+type buildTarget struct {
+	agentPath
+	rules int
+	files map[string][]byte
+	// changed are the files whose content differs from files; stale are
+	// generated files that are no longer produced.
+	changed []string
+	stale   []string
+	// edited are generated files changed by hand; foreign are files that
+	// build did not generate but would replace or remove.
+	edited  []string
+	foreign []string
+}
+
+func (t buildTarget) upToDate() bool {
+	return len(t.changed) == 0 && len(t.stale) == 0 && len(t.edited) == 0 && len(t.foreign) == 0
+}
+
+func runBuild(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	targets, err := planBuild(cfg)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("none of the paths in aIAgentMemoryContextPath belongs to a known agent - nothing to build")
+	}
+
+	outdated, conflicts := 0, 0
+	for _, t := range targets {
+		reportGenerated(cfg, t)
+		conflicts += len(t.edited) + len(t.foreign)
+		if !t.upToDate() {
+			outdated++
+		}
+	}
+
+	if buildCheck {
+		if outdated > 0 {
+			return fmt.Errorf("%d agent path(s) are out of date with %s - run 'ai-docs build'", outdated, cfg.DocDir)
+		}
+		printSuccess("Every agent path is up to date with %s", cfg.DocDir)
+		return nil
+	}
+	if conflicts > 0 && !force {
+		return fmt.Errorf("%d file(s) would lose changes made outside %s - move them into the fragments or rerun with --force", conflicts, cfg.DocDir)
+	}
+
+	if outdated == 0 {
+		printSuccess("Every agent path is already up to date with %s", cfg.DocDir)
+		return nil
+	}
+	for _, t := range targets {
+		if t.upToDate() {
+			printInfo("Up to date: %s", t.path)
+			continue
+		}
+		if dryRun {
+			for _, file := range t.changed {
+				printInfo("Would write %s", file)
+			}
+			for _, file := range t.stale {
+				printInfo("Would remove %s", file)
+			}
+			continue
+		}
+		written, removed, err := convert.WriteFiles(t.path, t.format, t.files)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", t.path, err)
+		}
+		for _, file := range append(written, removed...) {
+			printInfo("Updated %s", file)
+		}
+		printSuccess("Built %s from %d fragment(s)", t.path, t.rules)
+	}
+	if dryRun {
+		printWarning("Dry run mode - no files written")
+	}
+
+	return nil
+}
+
+// planBuild renders the fragments in cfg.DocDir for every known agent with a
+// configured path, into the first such path of the agent, and compares the
+// result with the files on disk.
+func planBuild(cfg *config.Config) ([]buildTarget, error) {
+	if !utils.PathExists(cfg.DocDir) {
+		return nil, fmt.Errorf("fragments directory %s does not exist - create it or set docDir", cfg.DocDir)
+	}
+	fragments, err := convert.ReadFragments(cfg.DocDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fragments: %w", err)
+	}
+
+	var targets []buildTarget
+	for _, agent := range agents.All() {
+		i, ok := cfg.ConfiguredPath(agent)
+		if !ok || i >= len(agent.Formats) {
+			continue
+		}
+		p := agent.Paths[i]
+		ap := agentPath{agent: agent, path: p, format: convert.Format(agent.Formats[i])}
+
+		var rules []convert.Rule
+		for _, f := range fragments {
+			if f.For(ap.agent) {
+				rules = append(rules, f.Rule)
+			}
+		}
+		t := buildTarget{agentPath: ap, rules: len(rules), files: map[string][]byte{}}
+		for file, data := range convert.Render(p, ap.format, rules) {
+			t.files[file] = convert.AddHeader(data, cleanSlash(cfg.DocDir))
+		}
+
+		existing, err := convert.Existing(p, ap.format)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range existing {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			generated, edited := convert.CheckHeader(data)
+			switch {
+			case !generated:
+				t.foreign = append(t.foreign, file)
+			case edited:
+				t.edited = append(t.edited, file)
+			}
+			if _, ok := t.files[file]; !ok {
+				t.stale = append(t.stale, file)
+			}
+		}
+		for file, data := range t.files {
+			if old, err := os.ReadFile(file); err != nil || !bytes.Equal(sources.Strip(old), data) {
+				t.changed = append(t.changed, file)
+			}
+		}
+		sort.Strings(t.changed)
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// reportGenerated warns about files of t that were edited by hand or not
+// generated by build.
+func reportGenerated(cfg *config.Config, t buildTarget) {
+	for _, file := range t.edited {
+		printWarning("%s was edited directly - make the change in %s and run 'ai-docs build'", file, cfg.DocDir)
+	}
+	for _, file := range t.foreign {
+		printWarning("%s was not generated by 'ai-docs build' and would be replaced or removed", file)
+	}
+}
+
+// checkGenerated flags generated agent files that were edited directly. It
+// does nothing unless the fragments directory exists.
+func checkGenerated(cfg *config.Config) {
+	if !utils.PathExists(cfg.DocDir) {
+		return
+	}
+	targets, err := planBuild(cfg)
+	if err != nil {
+		printWarning("Could not check generated files: %v", err)
+		return
+	}
+	for _, t := range targets {
+		for _, file := range t.edited {
+			printWarning("%s was edited directly - the change is lost on the next 'ai-docs build' unless you move it to %s", file, cfg.DocDir)
+		}
+		if len(t.edited) == 0 && (len(t.changed) > 0 || len(t.stale) > 0) && len(t.foreign) == 0 {
+			printWarning("%s is out of date with %s - run 'ai-docs build'", t.path, cfg.DocDir)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// Status of a CI check.
+const (
+	ciPass = "pass"
+	ciWarn = "warn"
+	ciFail = "fail"
+	ciSkip = "skip"
+)
+
+var (
+	ciFormat string
+	ciOutput string
+	ciBranch string
+)
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Commands for continuous integration",
+}
+
+var ciCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Verify that the repository and its doc branch are in a consistent state",
+	Long: `Runs non-interactive checks suited to CI: the config is valid, no agent
+files are tracked on the checked out branch, the ignore rules cover the agent
+paths, the doc branch exists on origin and can be fetched, and the commit in
+the lock file matches it. Only the doc branch is fetched, so shallow clones
+work.
+
+The report is written as text, JSON or JUnit XML, without colors. The exit
+code is 0 if every check passed (warnings included), 1 if a check failed, 2
+for an invalid config, 3 outside a git repository, 4 if the doc branch does
+not exist on origin and 5 if origin cannot be reached.`,
+	Args: cobra.NoArgs,
+	RunE: runCICheck,
+}
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+	ciCmd.AddCommand(ciCheckCmd)
+	ciCheckCmd.Flags().StringVar(&ciFormat, "format", "text", "report format: text, json or junit")
+	ciCheckCmd.Flags().StringVarP(&ciOutput, "output", "o", "", "write the report to this file instead of stdout")
+	ciCheckCmd.Flags().StringVar(&ciBranch, "branch", "", "doc branch to check (default: from the config, or the branch in the lock file)")
+}
+
+// ciResult is the outcome of one check.
+type ciResult struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+	// code is the exit code of a failed check. When several checks fail,
+	// the code of the first one is used.
+	code int
+}
+
+type ciReport struct {
+	Passed   bool       `json:"passed"`
+	ExitCode int        `json:"exitCode"`
+	Checks   []ciResult `json:"checks"`
+}
+
+func runCICheck(cmd *cobra.Command, args []string) error {
+	switch ciFormat {
+	case "text", "json", "junit":
+	default:
+		return &exitError{code: exitFailure, err: fmt.Errorf("unknown format %q - use text, json or junit", ciFormat)}
+	}
+	color.NoColor = true
+
+	report := ciReport{Checks: runCIChecks(cmd.Context())}
+	for _, r := range report.Checks {
+		if r.Status == ciFail && report.ExitCode == 0 {
+			report.ExitCode = r.code
+		}
+	}
+	report.Passed = report.ExitCode == 0
+
+	out := io.Writer(os.Stdout)
+	if ciOutput != "" {
+		f, err := os.Create(ciOutput)
+		if err != nil {
+			return &exitError{code: exitFailure, err: fmt.Errorf("failed to create %s: %w", ciOutput, err)}
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeCIReport(out, ciFormat, report); err != nil {
+		return &exitError{code: exitFailure, err: fmt.Errorf("failed to write report: %w", err)}
+	}
+
+	if !report.Passed {
+		failed := 0
+		for _, r := range report.Checks {
+			if r.Status == ciFail {
+				failed++
+			}
+		}
+		return &exitError{code: report.ExitCode, silent: true, err: fmt.Errorf("%d check(s) failed", failed)}
+	}
+	return nil
+}
+
+// runCIChecks runs the checks in order. Checks that depend on a failed one
+// are skipped.
+func runCIChecks(ctx context.Context) []ciResult {
+	results := []ciResult{}
+	add := func(r ciResult) { results = append(results, r) }
+	skipRest := func(reason string, names ...string) []ciResult {
+		for _, name := range names {
+			add(ciResult{Name: name, Status: ciSkip, Message: reason})
+		}
+		return results
+	}
+
+	if !utils.IsGitRepo() {
+		add(ciResult{Name: "Git repository", Status: ciFail, Message: "not a git repository", code: exitNotRepo})
+		return skipRest("not a git repository", "Configuration", "Agent files on branch", "Ignore rules", "Doc branch", "Lock file")
+	}
+	add(ciResult{Name: "Git repository", Status: ciPass, Message: "inside a git repository"})
+
+	cfg, err := loadConfig()
+	if err != nil {
+		add(ciResult{Name: "Configuration", Status: ciFail, Message: err.Error(), code: exitConfig})
+		return skipRest("no valid configuration", "Agent files on branch", "Ignore rules", "Doc branch", "Lock file")
+	}
+	add(ciConfigCheck(cfg))
+	add(ciTrackedCheck(ctx, cfg))
+	add(ciIgnoreCheck(ctx, cfg))
+
+	git := utils.GitClient()
+	branch, lock, lockErr := ciDocBranch(ctx, git, cfg)
+	docBranch := ciDocBranchCheck(ctx, git, branch)
+	add(docBranch)
+	if docBranch.Status != ciPass {
+		return skipRest("doc branch is not available", "Lock file")
+	}
+	add(ciLockCheck(ctx, cfg, lock, lockErr))
+	return results
+}
+
+func ciConfigCheck(cfg *config.Config) ciResult {
+	r := ciResult{Name: "Configuration", Status: ciPass, Message: "configuration is valid"}
+	issues := cfg.Validate()
+	for _, issue := range issues {
+		r.Details = append(r.Details, issue.String())
+	}
+	switch {
+	case config.HasErrors(issues):
+		r.Status, r.Message, r.code = ciFail, "configuration is invalid", exitConfig
+	case len(issues) > 0:
+		r.Status, r.Message = ciWarn, fmt.Sprintf("configuration is valid with %d warning(s)", len(issues))
+	}
+	return r
+}
+
+func ciTrackedCheck(ctx context.Context, cfg *config.Config) ciResult {
+	r := ciResult{Name: "Agent files on branch"}
+	tracked, err := trackedAgentFiles(ctx, cfg)
+	switch {
+	case err != nil:
+		r.Status, r.Message, r.code = ciFail, err.Error(), exitFailure
+	case len(tracked) > 0:
+		r.Status, r.Message, r.code = ciFail, fmt.Sprintf("%d agent file(s) are committed - they belong on the doc branch", len(tracked)), exitFailure
+		r.Details = tracked
+	default:
+		r.Status, r.Message = ciPass, "no agent files are committed"
+	}
+	return r
+}
+
+func ciIgnoreCheck(ctx context.Context, cfg *config.Config) ciResult {
+	r := ciResult{Name: "Ignore rules"}
+	if cfg.GitignoreTarget == config.GitignoreTargetExclude {
+		r.Status, r.Message = ciSkip, "ignore rules are kept in .git/info/exclude, which is not checked in"
+		return r
+	}
+	problems, err := checkIgnoreRules(ctx, cfg)
+	switch {
+	case err != nil:
+		r.Status, r.Message = ciWarn, err.Error()
+	case len(problems) > 0:
+		r.Status, r.Message = ciWarn, problems[0].summary
+		r.Details = []string{problems[0].detail}
+	default:
+		r.Status, r.Message = ciPass, "the ignore rules cover the agent paths"
+	}
+	return r
+}
+
+// ciDocBranch returns the doc branch to check and the lock file, if any.
+// The user name in a CI job rarely matches the one the doc branch was
+// created with, so the branch recorded in a committed lock file is preferred
+// over the one from the config.
+func ciDocBranch(ctx context.Context, git utils.Git, cfg *config.Config) (string, *lockFile, error) {
+	var lock *lockFile
+	path, err := lockPath(ctx, git, cfg)
+	if err == nil && cfg.LockFile != config.LockFileLocal && utils.PathExists(path) {
+		lock, err = loadLock(path)
+	}
+	switch {
+	case ciBranch != "":
+		return ciBranch, lock, err
+	case lock != nil && lock.DocBranch != "":
+		return lock.DocBranch, lock, err
+	}
+	return cfg.GetDocBranchName(), lock, err
+}
+
+// ciDocBranchCheck fetches the doc branch, and nothing else, from origin.
+func ciDocBranchCheck(ctx context.Context, git utils.Git, docBranch string) ciResult {
+	r := ciResult{Name: "Doc branch"}
+	exists, err := git.RemoteBranchExists(ctx, "origin", docBranch)
+	if err != nil {
+		r.Status, r.Message, r.code = ciFail, fmt.Sprintf("cannot reach origin: %v", err), exitNetwork
+		return r
+	}
+	if !exists {
+		r.Status, r.Message, r.code = ciFail, fmt.Sprintf("origin/%s does not exist - run 'ai-docs init' and 'ai-docs push'", docBranch), exitNotInitialized
+		return r
+	}
+	if err := git.Fetch(ctx, "origin", docBranch); err != nil {
+		r.Status, r.Message, r.code = ciFail, fmt.Sprintf("failed to fetch origin/%s: %v", docBranch, err), exitNetwork
+		return r
+	}
+	commit, err := git.ResolveRef(ctx, "refs/remotes/origin/"+docBranch)
+	if err != nil {
+		r.Status, r.Message, r.code = ciFail, err.Error(), exitNetwork
+		return r
+	}
+	r.Status, r.Message = ciPass, fmt.Sprintf("origin/%s is at %s", docBranch, commit[:7])
+	return r
+}
+
+// ciLockCheck checks that the doc branch commit in the committed lock file
+// exists and matches the recorded hashes.
+func ciLockCheck(ctx context.Context, cfg *config.Config, lock *lockFile, err error) ciResult {
+	r := ciResult{Name: "Lock file"}
+	switch {
+	case err != nil:
+		r.Status, r.Message, r.code = ciFail, err.Error(), exitFailure
+		return r
+	case cfg.LockFile == config.LockFileLocal:
+		r.Status, r.Message = ciSkip, "the lock file is kept in the git directory"
+		return r
+	case lock == nil:
+		r.Status, r.Message = ciSkip, fmt.Sprintf("no %s", lockName)
+		return r
+	case lock.Commit == "":
+		r.Status, r.Message = ciSkip, fmt.Sprintf("%s records no doc branch commit", lockName)
+		return r
+	}
+
+	files, err := branchFiles(ctx, cfg, lock.Commit)
+	if err == nil {
+		err = lock.verify(files, lockName)
+	}
+	if err != nil {
+		r.Status, r.Message, r.code = ciFail, fmt.Sprintf("locked commit %s cannot be used: %v", shortCommit(lock.Commit), err), exitFailure
+		return r
+	}
+	r.Status, r.Message = ciPass, fmt.Sprintf("locked commit %s matches %d file hash(es)", shortCommit(lock.Commit), len(files))
+	return r
+}
+
+func writeCIReport(w io.Writer, format string, report ciReport) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case "junit":
+		return writeJUnit(w, report)
+	}
+
+	for _, r := range report.Checks {
+		if _, err := fmt.Fprintf(w, "%-4s  %s: %s\n", strings.ToUpper(r.Status), r.Name, r.Message); err != nil {
+			return err
+		}
+		for _, d := range r.Details {
+			if _, err := fmt.Fprintf(w, "      %s\n", d); err != nil {
+				return err
+			}
+		}
+	}
+	result := "passed"
+	if !report.Passed {
+		result = fmt.Sprintf("failed (exit code %d)", report.ExitCode)
+	}
+	_, err := fmt.Fprintf(w, "ai-docs ci check %s\n", result)
+	return err
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, report ciReport) error {
+	suite := junitSuite{Name: "ai-docs ci check", Tests: len(report.Checks)}
+	for _, r := range report.Checks {
+		c := junitCase{Name: r.Name, ClassName: "ai-docs.ci"}
+		details := strings.Join(r.Details, "\n")
+		switch r.Status {
+		case ciFail:
+			suite.Failures++
+			c.Failure = &junitMessage{Message: r.Message, Text: details}
+		case ciSkip:
+			suite.Skipped++
+			c.Skipped = &junitMessage{Message: r.Message}
+		default:
+			c.SystemOut = strings.TrimSpace(strings.ToUpper(r.Status) + ": " + r.Message + "\n" + details)
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -11,41 +11,54 @@ import (
 	"github.com/trknhr/ai-docs/utils"
 )
 
+var (
+	archivePath  string
+	deleteRemote bool
+	noRestore    bool
+)
+
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Remove AI docs worktree and branch",
-	Long:  `Removes the AI docs worktree and optionally deletes the branch after confirmation.`,
-	RunE:  runClean,
+	Long: `Copies the latest AI memory files back into the working tree, then removes the
+AI docs worktree and local branch after confirmation. The branch can be exported
+with --archive first; the remote branch is only deleted with --delete-remote.`,
+	RunE: runClean,
 }
 
 func init() {
 	rootCmd.AddCommand(cleanCmd)
+	cleanCmd.Flags().BoolVar(&force, "force", false, "skip the confirmation prompt")
+	cleanCmd.Flags().StringVar(&archivePath, "archive", "", "export the doc branch to this archive (.tar.gz, .tar or .zip) before deleting it")
+	cleanCmd.Flags().BoolVar(&deleteRemote, "delete-remote", false, "also delete the doc branch on origin")
+	cleanCmd.Flags().BoolVar(&noRestore, "no-restore", false, "do not copy memory files back into the working tree")
+	cleanCmd.Flags().BoolVar(&overwrite, "overwrite", false, "overwrite existing local files when restoring")
 }
 
 func runClean(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if !utils.IsGitRepo() {
-		return fmt.Errorf("not a git repository")
+		return errNotRepo
 	}
 
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	git := utils.GitClient()
 	docBranch := cfg.GetDocBranchName()
 
 	if !force {
 		fmt.Printf("This will remove the worktree at '%s' and the branch '%s'.\n", cfg.DocWorktreeDir, docBranch)
-		fmt.Print("Are you sure? (y/N): ")
-
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
+		if deleteRemote {
+			fmt.Printf("The remote branch 'origin/%s' will also be deleted.\n", docBranch)
+		}
+		confirmed, err := promptYesNo(ctx, "Are you sure?", false)
 		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
+			return err
 		}
-
-		response = strings.ToLower(strings.TrimSpace(response))
-		if response != "y" && response != "yes" {
+		if !confirmed {
 			fmt.Println("Clean cancelled")
 			return nil
 		}
@@ -53,23 +66,55 @@ func runClean(cmd *cobra.Command, args []string) error {
 
 	if dryRun {
 		printWarning("Dry run mode - showing what would be done")
+		if archivePath != "" {
+			fmt.Printf("Would archive branch %s to: %s\n", docBranch, archivePath)
+		}
+		if !noRestore {
+			fmt.Printf("Would restore memory files from: %s\n", cfg.DocWorktreeDir)
+		}
 		fmt.Printf("Would remove worktree: %s\n", cfg.DocWorktreeDir)
 		fmt.Printf("Would delete branch: %s\n", docBranch)
+		if deleteRemote {
+			fmt.Printf("Would delete remote branch: origin/%s\n", docBranch)
+		}
+		fmt.Println("Would remove the ai-docs block from .gitignore and info/exclude")
 		return nil
 	}
 
+	if archivePath != "" {
+		if !git.BranchExists(ctx, docBranch) {
+			return fmt.Errorf("cannot archive: doc branch '%s' does not exist", docBranch)
+		}
+		printInfo("Archiving branch %s to %s", docBranch, archivePath)
+		if err := archiveDocBranch(ctx, cfg, docBranch, archivePath); err != nil {
+			return fmt.Errorf("failed to archive doc branch: %w", err)
+		}
+		printSuccess("Archived %s to %s", docBranch, archivePath)
+	}
+
 	for _, path := range cfg.AIAgentMemoryContextPath {
 		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
 			printInfo("Removing symlink: %s", path)
 			if err := os.Remove(path); err != nil {
-				printWarning("Failed to remove symlink %s: %v", path, err)
+				if err := stepFailed(fmt.Errorf("failed to remove symlink %s: %w", path, err)); err != nil {
+					return err
+				}
 			}
 		}
 	}
 
+	restored := false
+	if !noRestore && utils.PathExists(cfg.DocWorktreeDir) {
+		printInfo("Restoring memory files from %s", cfg.DocWorktreeDir)
+		if _, _, err := copyToLocal(ctx, cfg, cfg.DocWorktreeDir, true); err != nil {
+			return fmt.Errorf("failed to restore memory files: %w", err)
+		}
+		restored = true
+	}
+
 	if utils.PathExists(cfg.DocWorktreeDir) {
 		printInfo("Removing worktree: %s", cfg.DocWorktreeDir)
-		if err := utils.RunGit("", "worktree", "remove", "-f", cfg.DocWorktreeDir); err != nil {
+		if err := git.RemoveWorktree(ctx, cfg.DocWorktreeDir); err != nil {
 			printWarning("Git worktree remove failed: %v", err)
 			printInfo("Attempting manual removal")
 			if err := os.RemoveAll(cfg.DocWorktreeDir); err != nil {
@@ -81,31 +126,104 @@ func runClean(cmd *cobra.Command, args []string) error {
 		printInfo("Worktree directory does not exist")
 	}
 
-	if utils.BranchExists(docBranch) {
-		currentBranch, err := utils.GetCurrentBranch()
+	if git.BranchExists(ctx, docBranch) {
+		currentBranch, err := git.CurrentBranch(ctx)
 		if err == nil && currentBranch == docBranch {
 			printInfo("Switching away from doc branch")
-			if err := utils.RunGit("", "switch", cfg.MainBranchName); err != nil {
+			if err := git.Switch(ctx, cfg.MainBranchName); err != nil {
 				return fmt.Errorf("failed to switch branch: %w", err)
 			}
 		}
 
 		printInfo("Deleting branch: %s", docBranch)
-		if err := utils.RunGit("", "branch", "-D", docBranch); err != nil {
+		if err := git.DeleteBranch(ctx, docBranch); err != nil {
 			return fmt.Errorf("failed to delete branch: %w", err)
 		}
 		printSuccess("Deleted branch")
 
+	} else {
+		printInfo("Branch does not exist")
+	}
+
+	if deleteRemote {
 		printInfo("Deleting remote branch")
-		if err := utils.RunGit("", "push", "origin", "--delete", docBranch); err != nil {
-			printWarning("Failed to delete remote branch: %v", err)
+		if err := git.DeleteRemoteBranch(ctx, "origin", docBranch); err != nil {
+			if err := stepFailed(withCode(exitNetwork, fmt.Errorf("failed to delete remote branch: %w", err))); err != nil {
+				return err
+			}
 		} else {
 			printSuccess("Deleted remote branch")
 		}
-	} else {
-		printInfo("Branch does not exist")
 	}
 
-	printSuccess("Clean completed successfully!")
+	if err := removeIgnoreBlocks(ctx); err != nil {
+		if err := stepFailed(fmt.Errorf("failed to remove ignore rules: %w", err)); err != nil {
+			return err
+		}
+	} else if restored {
+		reportUntracked(ctx, cfg)
+	}
+
+	if failures == 0 {
+		printSuccess("Clean completed successfully!")
+	}
 	return nil
 }
+
+func archiveFormat(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		return "zip"
+	case strings.HasSuffix(path, ".tar"):
+		return "tar"
+	default:
+		return "tar.gz"
+	}
+}
+
+// archiveDocBranch exports rev to path. With encryption on, the files are
+// decrypted first, as the restored copies are, so that the archive stays
+// readable without the key.
+func archiveDocBranch(ctx context.Context, cfg *config.Config, rev, path string) error {
+	if !cfg.EncryptionEnabled() {
+		return utils.GitClient().Archive(ctx, rev, archiveFormat(path), path)
+	}
+
+	enc, err := loadEncryptor(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	files, err := revFiles(ctx, rev)
+	if err != nil {
+		return err
+	}
+	for i, f := range files {
+		if files[i].Data, err = enc.Decrypt(f.Name, f.Data); err != nil {
+			return decryptError(cfg, err)
+		}
+	}
+	return utils.WriteArchive(ctx, path, archiveFormat(path), files)
+}
+
+// reportUntracked lists the restored memory files that are untracked on the
+// main branch now that they are no longer ignored.
+func reportUntracked(ctx context.Context, cfg *config.Config) {
+	var untracked []string
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		path := cfg.AIAgentMemoryContextPath[name]
+		if !utils.PathExists(path) {
+			continue
+		}
+		if tracked, err := utils.GitClient().TrackedFiles(ctx, path); err == nil && len(tracked) == 0 {
+			untracked = append(untracked, path)
+		}
+	}
+	if len(untracked) == 0 {
+		return
+	}
+	printWarning("These memory files are no longer ignored and are untracked on %s:", cfg.MainBranchName)
+	for _, path := range untracked {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Println("Commit them, delete them or add them to .gitignore.")
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+func TestCleanRestoresAndArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		encrypt bool
+	}{
+		{"plain", false},
+		{"encrypted", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			if tt.encrypt {
+				identity, err := age.GenerateX25519Identity()
+				if err != nil {
+					t.Fatal(err)
+				}
+				identityFile := filepath.Join(r.root, "identity.txt")
+				writeFile(t, identityFile, identity.String()+"\n")
+				writeFile(t, ".ai-docs.config.yml", testConfig+fmt.Sprintf("encryption:\n  recipients: [%s]\n  identityFile: %s\n", identity.Recipient(), identityFile))
+			}
+			if err := runCommand(t, "init", "--yes"); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove("CLAUDE.md"); err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, filepath.Join(".cursor", "rules", "a.mdc"), "mine\n")
+
+			archive := filepath.Join(r.root, "docs.tar.gz")
+			if err := runCommand(t, "clean", "--force", "--archive", archive); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := readFile(t, "CLAUDE.md"); got != "# Claude memory\n" {
+				t.Errorf("CLAUDE.md = %q, want it restored", got)
+			}
+			if got := readFile(t, filepath.Join(".cursor", "rules", "a.mdc")); got != "mine\n" {
+				t.Errorf("a.mdc = %q, want the local file kept", got)
+			}
+			if utils.PathExists(".mem") {
+				t.Error("worktree was kept")
+			}
+			if branches := r.git(t, r.work, "branch", "--list", testDocBranch); branches != "" {
+				t.Errorf("doc branch was kept: %s", branches)
+			}
+			if remote := r.git(t, r.work, "ls-remote", "--heads", "origin", testDocBranch); remote == "" {
+				t.Error("remote doc branch was deleted without --delete-remote")
+			}
+			if status := r.git(t, r.work, "status", "--porcelain", "--untracked-files=all"); !strings.Contains(status, "?? CLAUDE.md") {
+				t.Errorf("status:\n%s\nwant CLAUDE.md untracked", status)
+			}
+
+			files, err := utils.ReadArchive(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			archived := map[string]string{}
+			for _, f := range files {
+				archived[f.Name] = string(f.Data)
+			}
+			if got := archived["CLAUDE.md"]; got != "# Claude memory\n" {
+				t.Errorf("archived CLAUDE.md = %q, want the plaintext", got)
+			}
+			if got := archived[".cursor/rules/a.mdc"]; got != "rule a\n" {
+				t.Errorf("archived a.mdc = %q, want the plaintext", got)
+			}
+		})
+	}
+}
+
+func TestCleanDeleteRemote(t *testing.T) {
+	r := newTestRepo(t)
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(t, "clean", "--force", "--no-restore", "--delete-remote"); err != nil {
+		t.Fatal(err)
+	}
+	if remote := r.git(t, r.work, "ls-remote", "--heads", "origin", testDocBranch); remote != "" {
+		t.Errorf("remote doc branch was kept: %s", remote)
+	}
+	if gitignore, err := os.ReadFile(".gitignore"); err == nil && strings.Contains(string(gitignore), "CLAUDE.md") {
+		t.Errorf(".gitignore still ignores the memory files:\n%s", gitignore)
+	}
+}
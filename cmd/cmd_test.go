@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+const testConfig = `userName: tester
+mainBranchName: main
+docBranchNameTemplate: "@doc/{userName}"
+docWorktreeDir: .mem
+aIAgentMemoryContextPath:
+  Claude: CLAUDE.md
+  Cursor: .cursor/rules
+ignorePatterns:
+  - /CLAUDE.md
+  - /.cursor/rules/
+`
+
+// testRepo is a working repository with a bare origin, both in a temporary
+// directory. The test runs inside the working repository.
+type testRepo struct {
+	root   string
+	origin string
+	work   string
+}
+
+// newTestRepo creates a repository with one commit on main pushed to origin,
+// the test config and a CLAUDE.md and Cursor rule that are not committed.
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not found")
+	}
+	root := t.TempDir()
+	r := &testRepo{root: root, origin: filepath.Join(root, "origin.git"), work: filepath.Join(root, "work")}
+	r.git(t, root, "init", "-q", "--bare", "-b", "main", r.origin)
+	r.git(t, root, "init", "-q", "-b", "main", r.work)
+	r.git(t, r.work, "config", "user.name", "tester")
+	r.git(t, r.work, "config", "user.email", "tester@example.com")
+	writeFile(t, filepath.Join(r.work, "README.md"), "# proj\n")
+	writeFile(t, filepath.Join(r.work, ".ai-docs.config.yml"), testConfig)
+	r.git(t, r.work, "add", ".")
+	r.git(t, r.work, "commit", "-qm", "init")
+	r.git(t, r.work, "remote", "add", "origin", "file://"+r.origin)
+	r.git(t, r.work, "push", "-q", "origin", "main")
+	writeFile(t, filepath.Join(r.work, "CLAUDE.md"), "# Claude memory\n")
+	writeFile(t, filepath.Join(r.work, ".cursor", "rules", "a.mdc"), "rule a\n")
+	t.Chdir(r.work)
+	return r
+}
+
+// git runs the git binary in dir and returns its trimmed output.
+func (r *testRepo) git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// clone makes a second working repository of origin with the identity set.
+func (r *testRepo) clone(t *testing.T, name string) string {
+	t.Helper()
+	dir := filepath.Join(r.root, name)
+	r.git(t, r.root, "clone", "-q", "file://"+r.origin, dir)
+	r.git(t, dir, "config", "user.name", "tester")
+	r.git(t, dir, "config", "user.email", "tester@example.com")
+	return dir
+}
+
+// runCommand runs ai-docs with args in the current directory, starting from
+// default flag values and a fresh git client.
+func runCommand(t *testing.T, args ...string) error {
+	t.Helper()
+	resetFlags(rootCmd)
+	strict, failures, failedCode = false, 0, 0
+	configPath = ""
+	utils.SetGitClient(nil)
+	rootCmd.SetArgs(args)
+	return rootCmd.ExecuteContext(context.Background())
+}
+
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			s.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
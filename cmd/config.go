@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the ai-docs configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file",
+	Long: `Loads the configuration with strict decoding and checks for unknown keys,
+invalid branch names, overlapping agent paths and ignore patterns that do not
+match the agent paths.`,
+	RunE: runConfigValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		var unknownErr *config.UnknownKeyError
+		if errors.As(err, &unknownErr) {
+			printWarning("Remove or rename the unknown keys; valid keys are: %v", config.KnownKeys())
+		}
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	issues := cfg.Validate()
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			color.Red("✗ %s", issue)
+		} else {
+			printWarning("%s", issue)
+		}
+	}
+
+	if config.HasErrors(issues) {
+		return withCode(exitConfig, errors.New("configuration is invalid"))
+	}
+
+	if len(issues) == 0 {
+		printSuccess("Configuration is valid")
+	} else {
+		printSuccess("Configuration is valid (%d warning(s))", len(issues))
+	}
+
+	return nil
+}
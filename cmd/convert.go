@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/agents"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/convert"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+var (
+	convertFrom     string
+	convertTo       []string
+	convertFromPath string
+	convertToPath   string
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert --from <agent> --to <agent>[,<agent>...]",
+	Short: "Convert memory files from one agent's format to another's",
+	Long: `Reads the memory of one agent and writes it in the format of other agents:
+a single markdown file (CLAUDE.md, GEMINI.md, AGENTS.md), Cursor MDC rules
+with frontmatter, a directory of markdown rules or the Cline memory bank.
+Each "##" section of a markdown file becomes one rule file and back.
+
+Agent paths are taken from the config when it lists them, otherwise from the
+agent's default location. Existing target files are only replaced with --force.`,
+	Args: cobra.NoArgs,
+	RunE: runConvert,
+}
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+	convertCmd.Flags().StringVar(&convertFrom, "from", "", "agent to read, e.g. claude")
+	convertCmd.Flags().StringSliceVar(&convertTo, "to", nil, "agents to write, e.g. cursor,gemini")
+	convertCmd.Flags().StringVar(&convertFromPath, "from-path", "", "read from this path instead of the agent's")
+	convertCmd.Flags().StringVar(&convertToPath, "to-path", "", "write to this path instead of the agent's (single --to only)")
+	convertCmd.Flags().BoolVar(&force, "force", false, "replace existing target files")
+	convertCmd.MarkFlagRequired("from")
+	convertCmd.MarkFlagRequired("to")
+}
+
+func runConvert(cmd *cobra.Command, args []string) error {
+	if convertToPath != "" && len(convertTo) > 1 {
+		return fmt.Errorf("--to-path needs a single --to agent")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		// The agents' default paths do not need a config.
+		cfg = &config.Config{}
+	}
+
+	src, err := resolveAgentPath(cfg, convertFrom, convertFromPath, true)
+	if err != nil {
+		return err
+	}
+	rules, err := convert.Read(src.path, src.format)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src.path, err)
+	}
+	printInfo("Read %d rule(s) from %s (%s)", len(rules), src.path, src.format)
+
+	for _, id := range convertTo {
+		dst, err := resolveAgentPath(cfg, id, convertToPath, false)
+		if err != nil {
+			return err
+		}
+		if dst.path == src.path {
+			return fmt.Errorf("%s is both the source and the target", dst.path)
+		}
+		if utils.PathExists(dst.path) && !force {
+			return fmt.Errorf("%s already exists - use --force to replace it", dst.path)
+		}
+
+		if dryRun {
+			for file := range convert.Render(dst.path, dst.format, rules) {
+				printInfo("Would write %s", file)
+			}
+			printWarning("Dry run mode - %s not written", dst.path)
+			continue
+		}
+		if err := writeRules(dst, rules); err != nil {
+			return err
+		}
+		printSuccess("Converted %s to %s (%s)", src.path, dst.path, dst.format)
+	}
+
+	return nil
+}
+
+// agentPath is where an agent keeps its memory and in which format.
+type agentPath struct {
+	agent  agents.Agent
+	path   string
+	format convert.Format
+}
+
+// resolveAgentPath picks the memory path of agent id: override if set,
+// otherwise the agent's path listed in the config, falling back to an
+// existing path (for sources) or the agent's first path.
+func resolveAgentPath(cfg *config.Config, id, override string, source bool) (agentPath, error) {
+	agent, ok := agents.Lookup(id)
+	if !ok {
+		return agentPath{}, fmt.Errorf("unknown agent %q; known agents: %s", id, strings.Join(agents.IDs(), ", "))
+	}
+	if len(agent.Formats) == 0 {
+		return agentPath{}, fmt.Errorf("the memory format of %s is not supported", agent.Name)
+	}
+
+	i, ok := cfg.ConfiguredPath(agent)
+	if source && (!ok || !utils.PathExists(agent.Paths[i])) {
+		for j, p := range agent.Paths {
+			if utils.PathExists(p) {
+				i = j
+				break
+			}
+		}
+	}
+	ap := agentPath{agent: agent, path: agent.Paths[i], format: convert.Format(agent.Formats[i])}
+
+	if override != "" {
+		ap.path = override
+		// A file given for an agent whose chosen path is a directory, or the
+		// other way round, e.g. .cursorrules for Cursor.
+		if info, err := os.Stat(override); err == nil && info.IsDir() != ap.format.IsDir() {
+			for _, f := range agent.Formats {
+				if convert.Format(f).IsDir() == info.IsDir() {
+					ap.format = convert.Format(f)
+					break
+				}
+			}
+		}
+	}
+	if source && !utils.PathExists(ap.path) {
+		if override != "" {
+			return agentPath{}, fmt.Errorf("%s does not exist", override)
+		}
+		return agentPath{}, fmt.Errorf("no %s memory found at %s", agent.Name, strings.Join(agent.Paths, " or "))
+	}
+	return ap, nil
+}
+
+func writeRules(dst agentPath, rules []convert.Rule) error {
+	written, removed, err := convert.Write(dst.path, dst.format, rules)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst.path, err)
+	}
+	for _, file := range written {
+		printInfo("Wrote %s", file)
+	}
+	for _, file := range removed {
+		printInfo("Removed %s", file)
+	}
+	return nil
+}
+
+// deriveAgents regenerates the memory of the sync.derive.to agents from the
+// sync.derive.from agent, replacing their files.
+func deriveAgents(cfg *config.Config) error {
+	d := cfg.Sync.Derive
+	src, err := resolveAgentPath(cfg, d.From, "", true)
+	if err != nil {
+		return err
+	}
+	rules, err := convert.Read(src.path, src.format)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src.path, err)
+	}
+
+	for _, id := range d.To {
+		dst, err := resolveAgentPath(cfg, id, "", false)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			printInfo("Would derive %s from %s", dst.path, src.path)
+			continue
+		}
+		if err := writeRules(dst, rules); err != nil {
+			return err
+		}
+		printSuccess("Derived %s from %s", dst.path, src.path)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <file>...",
+	Short: "Print decrypted doc branch files",
+	Long: `Decrypts files from the doc worktree and writes them to stdout. Files that
+are not encrypted are printed as they are. git uses this command to show
+encrypted files as plain text in diffs.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDecrypt,
+}
+
+func init() {
+	rootCmd.AddCommand(decryptCmd)
+}
+
+func runDecrypt(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	enc, err := loadEncryptor(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range args {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if enc != nil {
+			if data, err = enc.Decrypt(path, data); err != nil {
+				return decryptError(cfg, err)
+			}
+		}
+		if _, err := os.Stdout.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
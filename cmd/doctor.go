@@ -0,0 +1,369 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+var (
+	doctorFix bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the AI docs setup for problems and optionally repair them",
+	Long: `Checks for stale worktree registrations, a worktree directory that is not a
+registered worktree, a doc branch without upstream, missing ignore entries, agent
+files tracked on the main branch and leftover symlinks. Use --fix to repair them;
+fixes that move a directory aside or push to origin ask first unless --force is set.`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "repair the problems that were found")
+	doctorCmd.Flags().BoolVar(&force, "force", false, "apply fixes that move files or push to origin without asking")
+}
+
+// doctorProblem is a single finding. fix is nil when it cannot be repaired automatically.
+type doctorProblem struct {
+	summary string
+	detail  string
+	fix     func() error
+}
+
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context, cfg *config.Config) ([]doctorProblem, error)
+}
+
+var doctorChecks = []doctorCheck{
+	{"Worktree registrations", checkStaleWorktrees},
+	{"Worktree directory", checkWorktreeDir},
+	{"Doc branch upstream", checkUpstream},
+	{"Ignore rules", checkIgnoreRules},
+	{"Agent files on main branch", checkTrackedAgentFiles},
+	{"Leftover symlinks", checkSymlinks},
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if !utils.IsGitRepo() {
+		return errNotRepo
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	remaining := 0
+	for _, check := range doctorChecks {
+		problems, err := check.run(ctx, cfg)
+		if err != nil {
+			printWarning("%s: check failed: %v", check.name, err)
+			remaining++
+			continue
+		}
+		if len(problems) == 0 {
+			printSuccess("%s", check.name)
+			continue
+		}
+
+		for _, p := range problems {
+			color.Red("✗ %s: %s", check.name, p.summary)
+			fmt.Printf("    %s\n", p.detail)
+
+			if !doctorFix || dryRun {
+				remaining++
+				continue
+			}
+			if p.fix == nil {
+				printWarning("Cannot be fixed automatically")
+				remaining++
+				continue
+			}
+			if err := p.fix(); err != nil {
+				printWarning("Fix failed: %v", err)
+				remaining++
+				continue
+			}
+			printSuccess("Fixed")
+		}
+	}
+
+	if remaining > 0 {
+		if !doctorFix {
+			return fmt.Errorf("found %d problem(s) - run 'ai-docs doctor --fix' to repair them", remaining)
+		}
+		return fmt.Errorf("%d problem(s) remain", remaining)
+	}
+
+	printSuccess("No problems found")
+	return nil
+}
+
+func checkStaleWorktrees(ctx context.Context, cfg *config.Config) ([]doctorProblem, error) {
+	git := utils.GitClient()
+	worktrees, err := git.ListWorktrees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []doctorProblem
+	for _, wt := range worktrees {
+		if !wt.Prunable && utils.PathExists(wt.Path) {
+			continue
+		}
+		problems = append(problems, doctorProblem{
+			summary: fmt.Sprintf("stale worktree registration for %s", wt.Path),
+			detail:  "The directory was deleted without 'git worktree remove'; git still considers the branch checked out there.",
+			fix: func() error {
+				return git.PruneWorktrees(ctx)
+			},
+		})
+	}
+	return problems, nil
+}
+
+func checkWorktreeDir(ctx context.Context, cfg *config.Config) ([]doctorProblem, error) {
+	git := utils.GitClient()
+	docBranch := cfg.GetDocBranchName()
+
+	registered, err := isRegisteredWorktree(ctx, cfg.DocWorktreeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.PathExists(cfg.DocWorktreeDir) {
+		if !git.BranchExists(ctx, docBranch) {
+			return []doctorProblem{{
+				summary: fmt.Sprintf("neither %s nor branch %s exist", cfg.DocWorktreeDir, docBranch),
+				detail:  "AI docs is not initialized in this repository; run 'ai-docs init'.",
+			}}, nil
+		}
+		return []doctorProblem{{
+			summary: fmt.Sprintf("worktree %s is missing", cfg.DocWorktreeDir),
+			detail:  fmt.Sprintf("Branch %s exists but is not checked out at %s, so push and pull cannot work.", docBranch, cfg.DocWorktreeDir),
+			fix: func() error {
+				if err := git.PruneWorktrees(ctx); err != nil {
+					return err
+				}
+				return git.AddWorktree(ctx, cfg.DocWorktreeDir, docBranch)
+			},
+		}}, nil
+	}
+
+	if registered {
+		return nil, nil
+	}
+
+	return []doctorProblem{{
+		summary: fmt.Sprintf("%s exists but is not a git worktree", cfg.DocWorktreeDir),
+		detail:  "The directory will be moved aside and the doc branch checked out in its place.",
+		fix: func() error {
+			if !git.BranchExists(ctx, docBranch) {
+				return fmt.Errorf("doc branch '%s' does not exist - run 'ai-docs init'", docBranch)
+			}
+			backup := fmt.Sprintf("%s.bak-%s", strings.TrimSuffix(cfg.DocWorktreeDir, "/"), time.Now().Format("20060102150405"))
+			if err := confirmFix(ctx, fmt.Sprintf("Move %s to %s?", cfg.DocWorktreeDir, backup)); err != nil {
+				return err
+			}
+			if err := os.Rename(cfg.DocWorktreeDir, backup); err != nil {
+				return err
+			}
+			printInfo("Moved %s to %s", cfg.DocWorktreeDir, backup)
+			if err := git.PruneWorktrees(ctx); err != nil {
+				return err
+			}
+			return git.AddWorktree(ctx, cfg.DocWorktreeDir, docBranch)
+		},
+	}}, nil
+}
+
+// confirmFix asks before a fix that moves the user's files or publishes to
+// origin. --force answers yes.
+func confirmFix(ctx context.Context, question string) error {
+	if force {
+		return nil
+	}
+	confirmed, err := promptYesNo(ctx, question, false)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errors.New("not confirmed - run 'ai-docs doctor --fix --force' to apply it")
+	}
+	return nil
+}
+
+func checkUpstream(ctx context.Context, cfg *config.Config) ([]doctorProblem, error) {
+	git := utils.GitClient()
+	docBranch := cfg.GetDocBranchName()
+	if !git.BranchExists(ctx, docBranch) {
+		return nil, nil
+	}
+	_, err := git.Upstream(ctx, docBranch)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, utils.ErrNoUpstream) {
+		return nil, err
+	}
+
+	return []doctorProblem{{
+		summary: fmt.Sprintf("branch %s has no upstream", docBranch),
+		detail:  "Without an upstream, 'ai-docs pull' cannot fetch changes made on other machines.",
+		fix: func() error {
+			exists, err := git.RemoteBranchExists(ctx, "origin", docBranch)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				if err := confirmFix(ctx, fmt.Sprintf("origin has no %s - push the local branch?", docBranch)); err != nil {
+					return err
+				}
+				if err := git.Push(ctx, "", "origin", docBranch); err != nil {
+					return err
+				}
+			}
+			if err := git.Fetch(ctx, "origin", docBranch); err != nil {
+				return err
+			}
+			return git.SetUpstream(ctx, "origin", docBranch)
+		},
+	}}, nil
+}
+
+func checkIgnoreRules(ctx context.Context, cfg *config.Config) ([]doctorProblem, error) {
+	target, _, err := ignoreFiles(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := utils.ReadManagedBlock(target)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]struct{}, len(existing))
+	for _, e := range existing {
+		present[e] = struct{}{}
+	}
+
+	var missing []string
+	for _, e := range cfg.IgnoreEntries() {
+		if _, ok := present[strings.TrimSpace(e)]; !ok {
+			missing = append(missing, e)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	return []doctorProblem{{
+		summary: fmt.Sprintf("%s is missing ai-docs entries: %s", target, strings.Join(missing, ", ")),
+		detail:  "Agent files that are not ignored can be committed to the main branch by accident.",
+		fix: func() error {
+			return syncIgnoreBlock(ctx, cfg)
+		},
+	}}, nil
+}
+
+func checkTrackedAgentFiles(ctx context.Context, cfg *config.Config) ([]doctorProblem, error) {
+	tracked, err := trackedAgentFiles(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracked) == 0 {
+		return nil, nil
+	}
+
+	return []doctorProblem{{
+		summary: fmt.Sprintf("%d agent file(s) tracked on the main branch: %s", len(tracked), strings.Join(tracked, ", ")),
+		detail:  "These files belong on the doc branch. The fix untracks them (keeping the local copies); commit the result afterwards.",
+		fix: func() error {
+			return utils.GitClient().Untrack(ctx, tracked...)
+		},
+	}}, nil
+}
+
+// trackedAgentFiles returns the files under the agent paths that are tracked
+// in the main working tree's index.
+func trackedAgentFiles(ctx context.Context, cfg *config.Config) ([]string, error) {
+	paths := make([]string, 0, len(cfg.AIAgentMemoryContextPath))
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		paths = append(paths, cfg.AIAgentMemoryContextPath[name])
+	}
+	return utils.GitClient().TrackedFiles(ctx, paths...)
+}
+
+func checkSymlinks(ctx context.Context, cfg *config.Config) ([]doctorProblem, error) {
+	var problems []doctorProblem
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		path := cfg.AIAgentMemoryContextPath[name]
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			problems = append(problems, doctorProblem{
+				summary: fmt.Sprintf("%s is a dangling symlink", path),
+				detail:  "Older versions of ai-docs linked agent paths into the worktree. The link will be removed.",
+				fix: func() error {
+					return os.Remove(path)
+				},
+			})
+			continue
+		}
+
+		problems = append(problems, doctorProblem{
+			summary: fmt.Sprintf("%s is a symlink to %s", path, target),
+			detail:  "Older versions of ai-docs linked agent paths into the worktree. The link will be replaced with a copy of its target.",
+			fix: func() error {
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+				return utils.CopyPath(ctx, target, path)
+			},
+		})
+	}
+	return problems, nil
+}
+
+func isRegisteredWorktree(ctx context.Context, dir string) (bool, error) {
+	worktrees, err := utils.GitClient().ListWorktrees(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	want := canonicalPath(dir)
+	for _, wt := range worktrees {
+		if canonicalPath(wt.Path) == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func canonicalPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// answer makes prompts read s from stdin for the rest of the test.
+func answer(t *testing.T, s string) {
+	old := stdinReader
+	stdinReader = bufio.NewReader(strings.NewReader(s))
+	t.Cleanup(func() { stdinReader = old })
+}
+
+func TestDoctor(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		breakRepo func(t *testing.T, r *testRepo)
+		check     func(t *testing.T, r *testRepo)
+	}{
+		{
+			name: "stale worktree registration",
+			breakRepo: func(t *testing.T, r *testRepo) {
+				if err := os.RemoveAll(".mem"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if got := r.git(t, ".mem", "rev-parse", "--abbrev-ref", "HEAD"); got != testDocBranch {
+					t.Errorf("worktree is on %q", got)
+				}
+			},
+		},
+		{
+			name: "worktree directory is not a worktree",
+			args: []string{"--force"},
+			breakRepo: func(t *testing.T, r *testRepo) {
+				r.git(t, r.work, "worktree", "remove", "--force", ".mem")
+				writeFile(t, filepath.Join(".mem", "notes.md"), "notes\n")
+			},
+			check: func(t *testing.T, r *testRepo) {
+				backups, _ := filepath.Glob(".mem.bak-*")
+				if len(backups) != 1 || readFile(t, filepath.Join(backups[0], "notes.md")) != "notes\n" {
+					t.Errorf("backups = %v, want one holding notes.md", backups)
+				}
+				if got := r.git(t, ".mem", "rev-parse", "--abbrev-ref", "HEAD"); got != testDocBranch {
+					t.Errorf("worktree is on %q", got)
+				}
+			},
+		},
+		{
+			name: "no upstream",
+			breakRepo: func(t *testing.T, r *testRepo) {
+				r.git(t, r.work, "branch", "--unset-upstream", testDocBranch)
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if got := r.git(t, r.work, "rev-parse", "--abbrev-ref", testDocBranch+"@{upstream}"); got != "origin/"+testDocBranch {
+					t.Errorf("upstream = %q", got)
+				}
+			},
+		},
+		{
+			name: "no upstream or remote branch",
+			args: []string{"--force"},
+			breakRepo: func(t *testing.T, r *testRepo) {
+				r.git(t, r.work, "push", "-q", "origin", "--delete", testDocBranch)
+				r.git(t, r.work, "branch", "--unset-upstream", testDocBranch)
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if remote := r.git(t, r.work, "ls-remote", "--heads", "origin", testDocBranch); remote == "" {
+					t.Error("doc branch was not pushed")
+				}
+			},
+		},
+		{
+			name: "missing ignore entries",
+			breakRepo: func(t *testing.T, r *testRepo) {
+				if err := os.Remove(".gitignore"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if got := readFile(t, ".gitignore"); !strings.Contains(got, "/CLAUDE.md") {
+					t.Errorf(".gitignore:\n%s", got)
+				}
+			},
+		},
+		{
+			name: "agent file tracked on main",
+			breakRepo: func(t *testing.T, r *testRepo) {
+				r.git(t, r.work, "add", "-f", "CLAUDE.md")
+				r.git(t, r.work, "commit", "-qm", "track CLAUDE.md")
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if tracked := r.git(t, r.work, "ls-files", "CLAUDE.md"); tracked != "" {
+					t.Errorf("still tracked: %s", tracked)
+				}
+				if got := readFile(t, "CLAUDE.md"); got != "# Claude memory\n" {
+					t.Errorf("CLAUDE.md = %q, want the local copy kept", got)
+				}
+			},
+		},
+		{
+			name: "symlink",
+			breakRepo: func(t *testing.T, r *testRepo) {
+				if err := os.Remove("CLAUDE.md"); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(filepath.Join(".mem", "CLAUDE.md"), "CLAUDE.md"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if info, err := os.Lstat("CLAUDE.md"); err != nil || info.Mode()&os.ModeSymlink != 0 {
+					t.Fatalf("CLAUDE.md is not a regular file: %v", err)
+				}
+				if got := readFile(t, "CLAUDE.md"); got != "# Claude memory\n" {
+					t.Errorf("CLAUDE.md = %q, want a copy of the link target", got)
+				}
+			},
+		},
+		{
+			name: "dangling symlink",
+			breakRepo: func(t *testing.T, r *testRepo) {
+				if err := os.Remove("CLAUDE.md"); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink("missing.md", "CLAUDE.md"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			check: func(t *testing.T, r *testRepo) {
+				if _, err := os.Lstat("CLAUDE.md"); !os.IsNotExist(err) {
+					t.Errorf("dangling symlink was kept: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			if err := runCommand(t, "init", "--yes"); err != nil {
+				t.Fatal(err)
+			}
+			if err := runCommand(t, "doctor"); err != nil {
+				t.Fatalf("doctor after init: %v", err)
+			}
+
+			tt.breakRepo(t, r)
+			if err := runCommand(t, "doctor"); err == nil {
+				t.Fatal("doctor found no problem")
+			}
+			if err := runCommand(t, append([]string{"doctor", "--fix"}, tt.args...)...); err != nil {
+				t.Fatalf("doctor --fix: %v", err)
+			}
+			tt.check(t, r)
+			if err := runCommand(t, "doctor"); err != nil {
+				t.Errorf("doctor after the fix: %v", err)
+			}
+		})
+	}
+}
+
+func TestDoctorFixAsksFirst(t *testing.T) {
+	r := newTestRepo(t)
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+	r.git(t, r.work, "push", "-q", "origin", "--delete", testDocBranch)
+	r.git(t, r.work, "branch", "--unset-upstream", testDocBranch)
+	r.git(t, r.work, "worktree", "remove", "--force", ".mem")
+	writeFile(t, filepath.Join(".mem", "notes.md"), "notes\n")
+
+	answer(t, "n\nn\n")
+	if err := runCommand(t, "doctor", "--fix"); err == nil {
+		t.Fatal("declined fixes were reported as applied")
+	}
+	if got := readFile(t, filepath.Join(".mem", "notes.md")); got != "notes\n" {
+		t.Errorf("notes.md = %q, want the directory left in place", got)
+	}
+	if remote := r.git(t, r.work, "ls-remote", "--heads", "origin", testDocBranch); remote != "" {
+		t.Errorf("doc branch was pushed without confirmation: %s", remote)
+	}
+
+	answer(t, "y\ny\n")
+	if err := runCommand(t, "doctor", "--fix"); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.PathExists(filepath.Join(".mem", ".git")) {
+		t.Error("worktree was not checked out")
+	}
+	if remote := r.git(t, r.work, "ls-remote", "--heads", "origin", testDocBranch); remote == "" {
+		t.Error("doc branch was not pushed after confirmation")
+	}
+}
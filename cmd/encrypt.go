@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+	"golang.org/x/term"
+)
+
+// passphraseEnv holds the passphrase when encryption.passphrase is set.
+const passphraseEnv = "AI_DOCS_PASSPHRASE"
+
+// diffDriver is the git diff driver that shows doc branch files decrypted.
+const diffDriver = "ai-docs"
+
+// decryptAttributes is written to the root of the doc worktree so that git
+// diff, log -p and show run encrypted files through 'ai-docs decrypt'.
+const decryptAttributes = `# Managed by ai-docs: git diff shows encrypted files decrypted.
+* diff=` + diffDriver + `
+/.gitattributes !diff
+`
+
+// recipientsFile is written to the root of the doc worktree and lists the
+// recipients its files are encrypted to, so that push can tell when they
+// have changed.
+const recipientsFile = ".ai-docs-recipients"
+
+// recipientsHeader starts recipientsFile.
+const recipientsHeader = "# Managed by ai-docs: the age recipients the files on this branch are encrypted to.\n"
+
+// recipientsRecord returns the content of recipientsFile for cfg, or nil in
+// passphrase mode, where a new passphrase cannot decrypt the old ciphertext
+// and files are re-encrypted anyway.
+func recipientsRecord(cfg *config.Config) []byte {
+	if !cfg.EncryptionEnabled() || cfg.Encryption.Passphrase {
+		return nil
+	}
+	recipients := slices.Clone(cfg.Encryption.Recipients)
+	slices.Sort(recipients)
+	return []byte(recipientsHeader + strings.Join(slices.Compact(recipients), "\n") + "\n")
+}
+
+// recipientsChanged reports whether the files under root may be encrypted
+// to other recipients than cfg lists. A missing record counts as a change.
+func recipientsChanged(cfg *config.Config, root string) bool {
+	record := recipientsRecord(cfg)
+	if record == nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(root, recipientsFile))
+	return err != nil || !bytes.Equal(data, record)
+}
+
+// recordRecipients writes the recipients of cfg to recipientsFile under root.
+func recordRecipients(cfg *config.Config, root string) error {
+	record := recipientsRecord(cfg)
+	if record == nil {
+		return nil
+	}
+	path := filepath.Join(root, recipientsFile)
+	if data, err := os.ReadFile(path); err == nil && bytes.Equal(data, record) {
+		return nil
+	}
+	return os.WriteFile(path, record, 0644)
+}
+
+// loadEncryptor returns the Encryptor for cfg, or nil if encryption is not
+// enabled. In passphrase mode the passphrase is read from AI_DOCS_PASSPHRASE
+// or, failing that, asked for on the terminal.
+func loadEncryptor(ctx context.Context, cfg *config.Config) (*utils.Encryptor, error) {
+	if !cfg.EncryptionEnabled() {
+		return nil, nil
+	}
+
+	var enc *utils.Encryptor
+	if cfg.Encryption.Passphrase {
+		passphrase, err := readPassphrase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if enc, err = utils.NewPassphraseEncryptor(passphrase); err != nil {
+			return nil, err
+		}
+	} else {
+		recipients := make([]age.Recipient, 0, len(cfg.Encryption.Recipients))
+		for _, r := range cfg.Encryption.Recipients {
+			recipient, err := age.ParseX25519Recipient(r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid encryption recipient %q: %w", r, err)
+			}
+			recipients = append(recipients, recipient)
+		}
+
+		identities, err := utils.ReadIdentities(cfg.Encryption.IdentityFilePath())
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		enc = utils.NewEncryptor(recipients, identities)
+	}
+
+	// Remembering which plaintext each ciphertext holds spares push a
+	// decryption per unchanged file, a full scrypt run with a passphrase.
+	if path, err := utils.GitClient().GitPath(ctx, "ai-docs/encrypted-hashes.json"); err == nil {
+		if err := enc.UseKnownFile(path); err != nil {
+			printWarning("Ignoring %v", err)
+		}
+	}
+	return enc, nil
+}
+
+func readPassphrase(ctx context.Context) (string, error) {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return p, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("encryption.passphrase is set but %s is empty and stdin is not a terminal", passphraseEnv)
+	}
+	state, err := term.GetState(fd)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase for the doc branch: ")
+	type result struct {
+		passphrase []byte
+		err        error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		p, err := term.ReadPassword(fd)
+		ch <- result{p, err}
+	}()
+
+	select {
+	case r := <-ch:
+		fmt.Fprintln(os.Stderr)
+		if r.err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", r.err)
+		}
+		if len(r.passphrase) == 0 {
+			return "", fmt.Errorf("empty passphrase")
+		}
+		return string(r.passphrase), nil
+	case <-ctx.Done():
+		// ReadPassword turned off echo; turn it back on before exiting.
+		_ = term.Restore(fd, state)
+		fmt.Fprintln(os.Stderr)
+		return "", ctx.Err()
+	}
+}
+
+// decryptError adds a hint on how to fix a missing identity to err.
+func decryptError(cfg *config.Config, err error) error {
+	switch {
+	case !errors.Is(err, utils.ErrNoIdentity):
+		return err
+	case cfg.Encryption.Passphrase:
+		return fmt.Errorf("%w - check the passphrase", err)
+	default:
+		return fmt.Errorf("%w - put your age identity in %s or set encryption.identityFile", err, cfg.Encryption.IdentityFilePath())
+	}
+}
+
+// setupDecryptedDiff writes the diff attributes to the doc worktree and
+// registers 'ai-docs decrypt' as the textconv filter of the diff driver.
+func setupDecryptedDiff(ctx context.Context, cfg *config.Config) error {
+	path := filepath.Join(cfg.DocWorktreeDir, ".gitattributes")
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, []byte(decryptAttributes)) {
+		if err := os.WriteFile(path, []byte(decryptAttributes), 0644); err != nil {
+			return err
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// git runs textconv from the worktree, so the config path must be absolute.
+	cfgPath, err := filepath.Abs(config.ResolvePath(configPath))
+	if err != nil {
+		return err
+	}
+	textconv := fmt.Sprintf("%s decrypt --config %s", shellQuote(exe), shellQuote(cfgPath))
+	return utils.GitClient().SetConfig(ctx, "diff."+diffDriver+".textconv", textconv)
+}
+
+// shellQuote quotes s for the POSIX shell git runs textconv commands with.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
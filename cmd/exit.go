@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// Exit codes. They are part of the command line interface, so scripts can
+// tell failures apart; do not renumber them.
+const (
+	exitFailure        = 1 // any other error
+	exitConfig         = 2 // the config is missing or invalid
+	exitNotRepo        = 3 // not run inside a git repository
+	exitNotInitialized = 4 // the doc branch or worktree does not exist
+	exitNetwork        = 5 // origin could not be reached, or a push failed
+	exitConflict       = 6 // changes conflict and must be resolved by hand
+	exitPartial        = 7 // the command finished, but some steps failed
+	exitInterrupted    = 130
+)
+
+var (
+	// strict turns per-file warnings and failed steps into errors.
+	strict bool
+	// failures counts the steps that failed without stopping the command.
+	failures int
+	// failedCode is the exit code for failures: the code of the first failed
+	// step, or exitPartial if it has no more specific one.
+	failedCode int
+)
+
+// exitError makes the process exit with code instead of 1. If silent is set,
+// the error has already been reported and is not printed again.
+type exitError struct {
+	code   int
+	silent bool
+	err    error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+func withCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+var errNotRepo = withCode(exitNotRepo, errors.New("not a git repository"))
+
+// notInitialized reports that ai-docs has not been set up.
+func notInitialized(format string, args ...interface{}) error {
+	return withCode(exitNotInitialized, fmt.Errorf(format+" - run 'ai-docs init' first", args...))
+}
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	var e *exitError
+	switch {
+	case errors.As(err, &e):
+		return e.code
+	case errors.Is(err, utils.ErrNotRepository):
+		return exitNotRepo
+	case errors.Is(err, utils.ErrConflict):
+		return exitConflict
+	case errors.Is(err, utils.ErrNetwork), errors.Is(err, utils.ErrAuthFailed),
+		errors.Is(err, utils.ErrNonFastForward), errors.Is(err, utils.ErrRemoteRejected):
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+// stepFailed reports a step that failed without stopping the command. It
+// is printed as a warning and makes the command exit with the code of err,
+// or exitPartial if err has none. With --strict, err is returned instead and
+// should stop the command.
+func stepFailed(err error) error {
+	if strict {
+		return err
+	}
+	printWarning("%v", err)
+	if failures == 0 {
+		if failedCode = exitCode(err); failedCode == exitFailure {
+			failedCode = exitPartial
+		}
+	}
+	failures++
+	return nil
+}
+
+// fileWarning prints a warning about a single file, such as one that was
+// skipped. With --strict it is returned as an error instead.
+func fileWarning(format string, args ...interface{}) error {
+	if strict {
+		return fmt.Errorf(format, args...)
+	}
+	printWarning(format, args...)
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+func gitErr(kind error) error {
+	return &utils.GitError{Args: []string{"push"}, Kind: kind, Err: errors.New("exit status 1")}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"plain error", errors.New("boom"), exitFailure},
+		{"with code", withCode(exitConfig, errors.New("bad config")), exitConfig},
+		{"wrapped code", fmt.Errorf("init: %w", withCode(exitConfig, errors.New("bad config"))), exitConfig},
+		{"not a repository", errNotRepo, exitNotRepo},
+		{"not initialized", notInitialized("no worktree"), exitNotInitialized},
+		{"git not a repository", gitErr(utils.ErrNotRepository), exitNotRepo},
+		{"git conflict", fmt.Errorf("rebase: %w", gitErr(utils.ErrConflict)), exitConflict},
+		{"git network", gitErr(utils.ErrNetwork), exitNetwork},
+		{"git auth", gitErr(utils.ErrAuthFailed), exitNetwork},
+		{"git non-fast-forward", gitErr(utils.ErrNonFastForward), exitNetwork},
+		{"git rejected", gitErr(utils.ErrRemoteRejected), exitNetwork},
+		{"git other", gitErr(utils.ErrNotFound), exitFailure},
+		{"code wins over git kind", withCode(exitConfig, gitErr(utils.ErrNetwork)), exitConfig},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStepFailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		errs   []error
+		want   int
+	}{
+		{name: "generic failure", errs: []error{errors.New("a")}, want: exitPartial},
+		{name: "first code wins", errs: []error{withCode(exitNetwork, errors.New("a")), gitErr(utils.ErrConflict)}, want: exitNetwork},
+		{name: "generic then specific", errs: []error{errors.New("a"), gitErr(utils.ErrConflict)}, want: exitPartial},
+		{name: "strict", strict: true, errs: []error{errors.New("a")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(s bool) { strict, failures, failedCode = s, 0, 0 }(strict)
+			strict, failures, failedCode = tt.strict, 0, 0
+
+			for _, err := range tt.errs {
+				returned := stepFailed(err)
+				if tt.strict != (returned != nil) {
+					t.Fatalf("stepFailed returned %v with strict %v", returned, tt.strict)
+				}
+			}
+			if tt.strict {
+				if failures != 0 {
+					t.Errorf("failures = %d in strict mode", failures)
+				}
+				return
+			}
+			if failures != len(tt.errs) || failedCode != tt.want {
+				t.Errorf("failures = %d, failedCode = %d, want %d, %d", failures, failedCode, len(tt.errs), tt.want)
+			}
+		})
+	}
+}
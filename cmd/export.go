@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/secrets"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// manifestName is the manifest file at the root of an export bundle.
+const manifestName = "ai-docs-manifest.json"
+
+// manifestVersion is the bundle format written by export. import rejects
+// bundles with a newer version.
+const manifestVersion = 1
+
+// bundleManifest describes the contents of an export bundle.
+type bundleManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	DocBranch string    `json:"docBranch"`
+	// Source is "branch" or "local"; Commit is set for "branch".
+	Source string        `json:"source"`
+	Commit string        `json:"commit,omitempty"`
+	Agents []bundleAgent `json:"agents"`
+	Files  []bundleFile  `json:"files"`
+}
+
+type bundleAgent struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type bundleFile struct {
+	Path   string `json:"path"`
+	Agent  string `json:"agent"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+var (
+	exportFormat string
+	exportRev    string
+	exportOutput string
+	exportLocal  bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Package the memory files into a tar.gz or zip bundle",
+	Long: `Packages every configured agent path from the doc branch (or, with --local,
+from the working tree) into an archive together with a manifest of agent
+names, paths, the source commit and file hashes. Encrypted files are
+decrypted; redacted values stay redacted. Use 'ai-docs import' to unpack it.`,
+	Args: cobra.NoArgs,
+	RunE: runExport,
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "archive format: tar.gz or zip (default: from --output, else tar.gz)")
+	exportCmd.Flags().StringVar(&exportRev, "rev", "", "doc branch revision to export (default: the doc branch)")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "bundle file to write (default: ai-docs-<date>.<format>)")
+	exportCmd.Flags().BoolVar(&exportLocal, "local", false, "export the local copies instead of the doc branch")
+	exportCmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "export even if possible secrets are found (asks for confirmation)")
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if !utils.IsGitRepo() {
+		return errNotRepo
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if exportLocal && exportRev != "" {
+		return fmt.Errorf("--local and --rev cannot be combined")
+	}
+
+	format := exportFormat
+	if format == "" {
+		format = "tar.gz"
+		if exportOutput != "" {
+			format = archiveFormat(exportOutput)
+		}
+	}
+	if format != "tar.gz" && format != "zip" {
+		return fmt.Errorf("unsupported format %q - use tar.gz or zip", format)
+	}
+	output := exportOutput
+	if output == "" {
+		output = fmt.Sprintf("ai-docs-%s.%s", time.Now().Format("20060102"), format)
+	}
+
+	git := utils.GitClient()
+	docBranch := cfg.GetDocBranchName()
+	manifest := bundleManifest{
+		Version:   manifestVersion,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		DocBranch: docBranch,
+	}
+
+	var files []utils.ArchiveFile
+	if exportLocal {
+		manifest.Source = "local"
+		printInfo("Exporting local copies")
+		files, err = localBundleFiles(ctx, cfg)
+	} else {
+		rev := exportRev
+		if rev == "" {
+			rev = docBranch
+		}
+		manifest.Source = "branch"
+		if manifest.Commit, err = git.ResolveRef(ctx, rev); err != nil {
+			return fmt.Errorf("cannot resolve %s: %w", rev, err)
+		}
+		printInfo("Exporting %s at %s", rev, manifest.Commit)
+		files, err = branchBundleFiles(ctx, cfg, rev)
+	}
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no memory files to export")
+	}
+
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		manifest.Agents = append(manifest.Agents, bundleAgent{Name: name, Path: cleanSlash(cfg.AIAgentMemoryContextPath[name])})
+	}
+	for _, f := range files {
+		sum := sha256.Sum256(f.Data)
+		manifest.Files = append(manifest.Files, bundleFile{
+			Path:   f.Name,
+			Agent:  agentFor(cfg, f.Name),
+			SHA256: hex.EncodeToString(sum[:]),
+			Size:   len(f.Data),
+		})
+	}
+
+	scanner, err := secretScanner(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid secretScan config: %w", err)
+	}
+	if scanner != nil {
+		var findings []secrets.Finding
+		for _, f := range files {
+			findings = append(findings, scanner.Scan(f.Name, f.Data)...)
+		}
+		if err := checkFindings(ctx, findings, "written to "+output); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		printWarning("Dry run mode - no changes will be made")
+		for _, f := range manifest.Files {
+			fmt.Printf("Would export %s (%s, %d bytes)\n", f.Path, f.Agent, f.Size)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files = append([]utils.ArchiveFile{{Name: manifestName, Mode: 0644, Data: append(data, '\n')}}, files...)
+
+	if err := utils.WriteArchive(ctx, output, format, files); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	printSuccess("Exported %d file(s) to %s", len(manifest.Files), output)
+	return nil
+}
+
+// branchBundleFiles returns the files under the agent paths at rev,
+// decrypted.
+func branchBundleFiles(ctx context.Context, cfg *config.Config, rev string) ([]utils.ArchiveFile, error) {
+	all, err := branchFiles(ctx, cfg, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := loadEncryptor(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if enc == nil {
+		return all, nil
+	}
+	for i, f := range all {
+		if all[i].Data, err = enc.Decrypt(f.Name, f.Data); err != nil {
+			return nil, decryptError(cfg, err)
+		}
+	}
+	return all, nil
+}
+
+// branchFiles returns the files under the agent paths at rev, as stored.
+func branchFiles(ctx context.Context, cfg *config.Config, rev string) ([]utils.ArchiveFile, error) {
+	all, err := revFiles(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []utils.ArchiveFile
+	for _, f := range all {
+		if agentFor(cfg, f.Name) != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// revFiles returns every file at rev, as stored.
+func revFiles(ctx context.Context, rev string) ([]utils.ArchiveFile, error) {
+	tmp, err := os.CreateTemp("", "ai-docs-export-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := utils.GitClient().Archive(ctx, rev, "tar", tmp.Name()); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rev, err)
+	}
+	return utils.ReadArchive(tmp.Name())
+}
+
+// localBundleFiles returns the files under the agent paths in the working
+// tree, redacted as they would be on push.
+func localBundleFiles(ctx context.Context, cfg *config.Config) ([]utils.ArchiveFile, error) {
+	redactor, mapping, err := loadRedactor(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []utils.ArchiveFile
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		root := cfg.AIAgentMemoryContextPath[name]
+		if !utils.PathExists(root) {
+			continue
+		}
+		err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if redactor != nil {
+				if data, err = redactor.Redact(file, data); err != nil {
+					return err
+				}
+			}
+			files = append(files, utils.ArchiveFile{Name: cleanSlash(file), Mode: info.Mode().Perm(), Data: data})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", root, err)
+		}
+	}
+
+	if mapping != nil {
+		if err := mapping.Save(); err != nil {
+			return nil, fmt.Errorf("failed to save redaction mapping: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// agentFor returns the name of the agent whose path contains name, or "".
+func agentFor(cfg *config.Config, name string) string {
+	for _, agent := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		if pathWithin(name, cleanSlash(cfg.AIAgentMemoryContextPath[agent])) {
+			return agent
+		}
+	}
+	return ""
+}
+
+// pathWithin reports whether the slash-separated path p is dir or inside it.
+func pathWithin(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+func cleanSlash(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+const gitignorePath = ".gitignore"
+
+// ignoreFiles returns the ignore file selected by the config and the one that
+// must not carry the ai-docs block.
+func ignoreFiles(ctx context.Context, cfg *config.Config) (target, other string, err error) {
+	excludePath, err := utils.GitClient().GitPath(ctx, "info/exclude")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to locate info/exclude: %w", err)
+	}
+
+	if cfg.GitignoreTarget == config.GitignoreTargetExclude {
+		return excludePath, gitignorePath, nil
+	}
+	return gitignorePath, excludePath, nil
+}
+
+// syncIgnoreBlock rewrites the ai-docs block in the configured ignore file and
+// removes any stale block from the other one.
+func syncIgnoreBlock(ctx context.Context, cfg *config.Config) error {
+	target, other, err := ignoreFiles(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	changed, err := utils.WriteManagedBlock(target, cfg.IgnoreEntries())
+	if err != nil {
+		return err
+	}
+	if changed {
+		printInfo("Updated ai-docs block in %s", target)
+	}
+
+	if removed, err := utils.RemoveManagedBlock(other); err != nil {
+		return err
+	} else if removed {
+		printInfo("Removed ai-docs block from %s", other)
+	}
+
+	return nil
+}
+
+// removeIgnoreBlocks deletes the ai-docs block from both .gitignore and info/exclude.
+func removeIgnoreBlocks(ctx context.Context) error {
+	excludePath, err := utils.GitClient().GitPath(ctx, "info/exclude")
+	if err != nil {
+		return fmt.Errorf("failed to locate info/exclude: %w", err)
+	}
+
+	for _, path := range []string{gitignorePath, excludePath} {
+		removed, err := utils.RemoveManagedBlock(path)
+		if err != nil {
+			return err
+		}
+		if removed {
+			printInfo("Removed ai-docs block from %s", path)
+		}
+	}
+	return nil
+}
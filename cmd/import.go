@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import memory files from an export bundle",
+	Long: `Validates the manifest of a bundle written by 'ai-docs export' and copies its
+files into the working tree. Each agent's files go to the path configured for
+that agent. As with pull, existing local files are kept unless --overwrite is
+given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().BoolVar(&overwrite, "overwrite", false, "overwrite existing local files")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if !utils.IsGitRepo() {
+		return errNotRepo
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	printStep(1, 4, "Reading bundle")
+	manifest, files, err := readBundle(args[0])
+	if err != nil {
+		return err
+	}
+	printInfo("Bundle of %s from %s, created %s", manifest.DocBranch, manifestSource(manifest), manifest.CreatedAt.Format("2006-01-02 15:04"))
+
+	printStep(2, 4, "Matching agent paths")
+	dests := map[string]string{}
+	for _, agent := range manifest.Agents {
+		dst := localAgentPath(cfg, agent)
+		if dst == "" {
+			printWarning("Agent %s (%s) is not configured here - add it to aIAgentMemoryContextPath to import it", agent.Name, agent.Path)
+			continue
+		}
+		dests[agent.Name] = dst
+		printInfo("%s: %s → %s", agent.Name, agent.Path, dst)
+	}
+
+	if dryRun {
+		printWarning("Dry run mode - no changes will be made")
+		for _, agent := range manifest.Agents {
+			if dst, ok := dests[agent.Name]; ok {
+				fmt.Printf("Would copy %s to %s\n", agent.Path, dst)
+			}
+		}
+		return nil
+	}
+
+	printStep(3, 4, "Copying files to local")
+	if err := syncIgnoreBlock(ctx, cfg); err != nil {
+		if err := stepFailed(fmt.Errorf("failed to update ignore rules: %w", err)); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "ai-docs-import-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	for _, f := range files {
+		name := filepath.Join(tmp, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(name, f.Data, f.Mode|0600); err != nil {
+			return err
+		}
+	}
+
+	var transform utils.Transform
+	if cfg.Redact != nil && cfg.Redact.ExpandOnPull {
+		redactor, _, err := loadRedactor(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if redactor != nil {
+			transform = redactor.Expand
+		}
+	}
+
+	copiedCount, skippedCount := 0, 0
+	for _, agent := range manifest.Agents {
+		dst, ok := dests[agent.Name]
+		if !ok {
+			continue
+		}
+		src := filepath.Join(tmp, filepath.FromSlash(agent.Path))
+		if !utils.PathExists(src) {
+			printInfo("Bundle has no files for %s (skipping)", agent.Name)
+			skippedCount++
+			continue
+		}
+		if utils.PathExists(dst) && !overwrite {
+			if err := fileWarning("Local file exists: %s (use --overwrite to replace)", dst); err != nil {
+				return err
+			}
+			skippedCount++
+			continue
+		}
+		if err := utils.CopyPathWith(ctx, src, dst, transform); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			if err := stepFailed(fmt.Errorf("failed to copy %s → %s: %w", agent.Path, dst, err)); err != nil {
+				return err
+			}
+			skippedCount++
+			continue
+		}
+		printSuccess("Copied: %s", dst)
+		copiedCount++
+	}
+
+	printStep(4, 4, "Import complete")
+	printInfo("Paths copied: %d, skipped: %d", copiedCount, skippedCount)
+	if skippedCount > 0 && !overwrite {
+		fmt.Println("\nUse --overwrite flag to replace existing local files")
+	}
+	return nil
+}
+
+// readBundle reads an export bundle and checks its files against the
+// manifest: every listed file must be present with the recorded hash, lie
+// inside its agent's path, and no unlisted files may be present.
+func readBundle(bundle string) (*bundleManifest, []utils.ArchiveFile, error) {
+	all, err := utils.ReadArchive(bundle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var manifest *bundleManifest
+	contents := map[string]utils.ArchiveFile{}
+	for _, f := range all {
+		f.Name = cleanSlash(f.Name)
+		if f.Name == manifestName {
+			manifest = &bundleManifest{}
+			if err := json.Unmarshal(f.Data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+		contents[f.Name] = f
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("%s is not an ai-docs bundle: %s is missing", bundle, manifestName)
+	}
+	if manifest.Version > manifestVersion {
+		return nil, nil, fmt.Errorf("bundle format version %d is newer than this ai-docs supports (%d) - upgrade ai-docs", manifest.Version, manifestVersion)
+	}
+
+	// Paths are compared cleaned, so that "a/../b" cannot pass as being
+	// inside "a".
+	agents := map[string]string{}
+	for i, a := range manifest.Agents {
+		if !filepath.IsLocal(filepath.FromSlash(a.Path)) {
+			return nil, nil, fmt.Errorf("invalid manifest: agent %s has unsafe path %q", a.Name, a.Path)
+		}
+		manifest.Agents[i].Path = cleanSlash(a.Path)
+		agents[a.Name] = manifest.Agents[i].Path
+	}
+
+	files := make([]utils.ArchiveFile, 0, len(manifest.Files))
+	for _, mf := range manifest.Files {
+		name := cleanSlash(mf.Path)
+		agentPath, ok := agents[mf.Agent]
+		if !ok || !pathWithin(name, agentPath) || !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, nil, fmt.Errorf("invalid manifest: %s is not inside the path of agent %q", mf.Path, mf.Agent)
+		}
+		f, ok := contents[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("bundle is incomplete: %s is listed in the manifest but missing", mf.Path)
+		}
+		sum := sha256.Sum256(f.Data)
+		if hex.EncodeToString(sum[:]) != mf.SHA256 || len(f.Data) != mf.Size {
+			return nil, nil, fmt.Errorf("bundle is corrupt: %s does not match its hash in the manifest", mf.Path)
+		}
+		delete(contents, name)
+		files = append(files, f)
+	}
+	for name := range contents {
+		return nil, nil, fmt.Errorf("bundle contains %s, which is not listed in the manifest", name)
+	}
+
+	return manifest, files, nil
+}
+
+// localAgentPath returns where an agent's files from a bundle go: the path
+// configured for the agent's name, or the bundle path if it is configured
+// for another agent. It returns "" if neither is configured.
+func localAgentPath(cfg *config.Config, agent bundleAgent) string {
+	if p, ok := cfg.AIAgentMemoryContextPath[agent.Name]; ok {
+		return p
+	}
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		if p := cfg.AIAgentMemoryContextPath[name]; cleanSlash(p) == agent.Path {
+			return p
+		}
+	}
+	return ""
+}
+
+func manifestSource(m *bundleManifest) string {
+	if m.Source == "branch" && len(m.Commit) >= 7 {
+		return "commit " + m.Commit[:7]
+	}
+	return "local copies"
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+func TestReadBundle(t *testing.T) {
+	files := map[string]string{
+		"CLAUDE.md":           "# Claude\n",
+		".cursor/rules/a.mdc": "rule\n",
+	}
+	agents := []bundleAgent{{Name: "Claude", Path: "CLAUDE.md"}, {Name: "Cursor", Path: ".cursor/rules"}}
+	entry := func(path, agent string) bundleFile {
+		sum := sha256.Sum256([]byte(files[path]))
+		return bundleFile{Path: path, Agent: agent, SHA256: hex.EncodeToString(sum[:]), Size: len(files[path])}
+	}
+	valid := func() *bundleManifest {
+		return &bundleManifest{
+			Version: manifestVersion,
+			Source:  "local",
+			Agents:  append([]bundleAgent(nil), agents...),
+			Files:   []bundleFile{entry("CLAUDE.md", "Claude"), entry(".cursor/rules/a.mdc", "Cursor")},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(m *bundleManifest, archive map[string]string)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(m *bundleManifest, archive map[string]string) {},
+		},
+		{
+			name: "archive names are cleaned",
+			modify: func(m *bundleManifest, archive map[string]string) {
+				archive["./CLAUDE.md"] = archive["CLAUDE.md"]
+				delete(archive, "CLAUDE.md")
+			},
+		},
+		{
+			name: "paths are cleaned",
+			modify: func(m *bundleManifest, archive map[string]string) {
+				m.Agents[1].Path = ".cursor/./rules/"
+				m.Files[1].Path = "./.cursor/rules/a.mdc"
+			},
+		},
+		{
+			name:    "no manifest",
+			modify:  func(m *bundleManifest, archive map[string]string) { delete(archive, manifestName) },
+			wantErr: "is not an ai-docs bundle",
+		},
+		{
+			name:    "newer version",
+			modify:  func(m *bundleManifest, archive map[string]string) { m.Version = manifestVersion + 1 },
+			wantErr: "upgrade ai-docs",
+		},
+		{
+			name:    "agent path outside the repository",
+			modify:  func(m *bundleManifest, archive map[string]string) { m.Agents[0].Path = "../CLAUDE.md" },
+			wantErr: "unsafe path",
+		},
+		{
+			name:    "absolute agent path",
+			modify:  func(m *bundleManifest, archive map[string]string) { m.Agents[0].Path = "/etc" },
+			wantErr: "unsafe path",
+		},
+		{
+			name: "file escapes its agent path",
+			modify: func(m *bundleManifest, archive map[string]string) {
+				m.Files[1].Path = ".cursor/rules/../../evil"
+				archive[".cursor/rules/../../evil"] = archive[".cursor/rules/a.mdc"]
+			},
+			wantErr: "is not inside the path of agent",
+		},
+		{
+			name:    "file of another agent",
+			modify:  func(m *bundleManifest, archive map[string]string) { m.Files[1].Agent = "Claude" },
+			wantErr: "is not inside the path of agent",
+		},
+		{
+			name:    "unknown agent",
+			modify:  func(m *bundleManifest, archive map[string]string) { m.Files[0].Agent = "Other" },
+			wantErr: "is not inside the path of agent",
+		},
+		{
+			name:    "listed file missing",
+			modify:  func(m *bundleManifest, archive map[string]string) { delete(archive, "CLAUDE.md") },
+			wantErr: "bundle is incomplete",
+		},
+		{
+			name:    "hash mismatch",
+			modify:  func(m *bundleManifest, archive map[string]string) { archive["CLAUDE.md"] = "# Changed\n" },
+			wantErr: "bundle is corrupt",
+		},
+		{
+			name:    "unlisted file",
+			modify:  func(m *bundleManifest, archive map[string]string) { archive["extra.md"] = "x\n" },
+			wantErr: "not listed in the manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid()
+			archive := map[string]string{manifestName: ""}
+			for name, data := range files {
+				archive[name] = data
+			}
+			tt.modify(m, archive)
+			if _, ok := archive[manifestName]; ok {
+				data, err := json.Marshal(m)
+				if err != nil {
+					t.Fatal(err)
+				}
+				archive[manifestName] = string(data)
+			}
+
+			var entries []utils.ArchiveFile
+			for name, data := range archive {
+				entries = append(entries, utils.ArchiveFile{Name: name, Mode: 0644, Data: []byte(data)})
+			}
+			bundle := filepath.Join(t.TempDir(), "bundle.tar")
+			if err := utils.WriteArchive(context.Background(), bundle, "tar", entries); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, got, err := readBundle(bundle)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(manifest.Agents, agents) || len(got) != 2 {
+				t.Errorf("got agents %v and %d files, want %v and 2", manifest.Agents, len(got), agents)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/include"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// maxIncludeSize bounds the size of a file downloaded for an include.
+const maxIncludeSize = 1 << 20
+
+// includeResolver resolves the targets of include directives on pull:
+// http(s) URLs are downloaded, "name:path" is read from the include.repos
+// entry name, and anything else is a path in the doc branch, checked out at
+// root.
+type includeResolver struct {
+	cfg     *config.Config
+	root    string
+	git     utils.Git
+	decrypt utils.Transform
+	// repos caches the checkout of each include repo for this run.
+	repos map[string]string
+}
+
+func newIncludeResolver(cfg *config.Config, root string, decrypt utils.Transform) *includeResolver {
+	return &includeResolver{cfg: cfg, root: root, git: utils.GitClient(), decrypt: decrypt, repos: map[string]string{}}
+}
+
+func (r *includeResolver) resolve(ctx context.Context, target string) ([]byte, error) {
+	if strings.Contains(target, "://") {
+		return fetchInclude(ctx, target)
+	}
+	if name, file, ok := strings.Cut(target, ":"); ok && r.cfg.Include != nil {
+		if repo, ok := r.cfg.Include.Repos[name]; ok {
+			dir, err := r.checkout(ctx, name, repo)
+			if err != nil {
+				return nil, err
+			}
+			return readWithin(dir, file)
+		}
+	}
+
+	data, err := readWithin(r.root, target)
+	if err != nil {
+		return nil, err
+	}
+	if r.decrypt != nil {
+		if data, err = r.decrypt(target, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// expand is the pull transform that expands include directives. Directives
+// that cannot be resolved are kept and reported, or fail the file with
+// --strict.
+func (r *includeResolver) expand(ctx context.Context) utils.Transform {
+	return func(dst string, data []byte) ([]byte, error) {
+		if !include.Has(data) {
+			return data, nil
+		}
+		out, errs := include.Expand(ctx, data, r.resolve)
+		for _, err := range errs {
+			if err := fileWarning("%s: %v", dst, err); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+}
+
+// checkout refreshes the cached checkout of an include repo, once per run.
+// If the repo cannot be reached, the previous checkout is used.
+func (r *includeResolver) checkout(ctx context.Context, name string, repo config.IncludeRepo) (string, error) {
+	if dir, ok := r.repos[name]; ok {
+		return dir, nil
+	}
+	dir, err := r.git.GitPath(ctx, filepath.Join("ai-docs", "include", name))
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := cloneInto(ctx, r.git, dir, repo.URL, repo.Ref)
+	switch {
+	case err == nil:
+		printInfo("Fetched include repo %s at %s", name, commit[:7])
+	case utils.PathExists(dir) && ctx.Err() == nil:
+		printWarning("Could not update include repo %s, using the cached copy: %v", name, err)
+	default:
+		return "", fmt.Errorf("failed to fetch include repo %s: %w", name, err)
+	}
+
+	r.repos[name] = dir
+	return dir, nil
+}
+
+// readWithin reads the slash-separated path rel below root, refusing paths
+// that leave root.
+func readWithin(root, rel string) ([]byte, error) {
+	clean := path.Clean("/" + rel)[1:]
+	if clean == "" || clean != strings.TrimPrefix(path.Clean(rel), "./") {
+		return nil, fmt.Errorf("invalid path %q", rel)
+	}
+	return os.ReadFile(filepath.Join(root, filepath.FromSlash(clean)))
+}
+
+func fetchInclude(ctx context.Context, url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return nil, fmt.Errorf("unsupported URL %q", url)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIncludeSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIncludeSize {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", url, maxIncludeSize)
+	}
+	return data, nil
+}
+
+// collapseIncludes is the push transform that removes included text, so
+// that the doc branch stores only the directives.
+func collapseIncludes(_ string, data []byte) ([]byte, error) {
+	return include.Collapse(data), nil
+}
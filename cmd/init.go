@@ -1,82 +1,141 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/trknhr/ai-docs/config"
 	"github.com/trknhr/ai-docs/utils"
 )
 
+var (
+	assumeYes bool
+	resume    bool
+)
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize AI docs branch and worktree",
-	Long:  `Creates an orphan branch for AI memory files, sets up worktree, and creates symlinks.`,
-	RunE:  runInit,
+	Long: `Creates an orphan branch for AI memory files without touching the current
+checkout, pushes it, and sets up a worktree for it.`,
+	RunE: runInit,
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().BoolVar(&force, "force", false, "force initialization even if branch/worktree exists")
+	initCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "accept the detected configuration without prompting")
+	initCmd.Flags().BoolVar(&resume, "resume", false, "continue an interrupted init")
+	initCmd.Flags().BoolVar(&overwrite, "overwrite", false, "overwrite local files when joining an existing doc branch")
+	initCmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "push even if possible secrets are found (asks for confirmation)")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if !utils.IsGitRepo() {
-		return fmt.Errorf("not a git repository")
+		return errNotRepo
 	}
 
-	printStep(1, 9, "Reading configuration")
-
-	// Check if config file exists, create scaffolding if not
-	if configPath == "" {
-		configPath = ".ai-docs.config.yml"
-	}
+	printStep(1, 8, "Reading configuration")
 
-	if !utils.PathExists(configPath) {
-		printWarning("Config file not found at: %s", configPath)
-		if err := createScaffoldingConfig(configPath); err != nil {
+	// Run the setup wizard if no config file exists yet
+	if !utils.PathExists(config.ResolvePath(configPath)) {
+		printWarning("Config file not found")
+		if dryRun {
+			printWarning("Dry run mode - run without --dry-run to create a config file")
+			return nil
+		}
+		path, err := runInitWizard(ctx, configPath, assumeYes)
+		if err != nil {
 			return fmt.Errorf("failed to create config file: %w", err)
 		}
-		printSuccess("Created sample config file: %s", configPath)
-		fmt.Println("\nPlease review and edit the configuration file, then run 'ai-docs init' again.")
-		return nil
+		configPath = path
 	}
 
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
-	printInfo("Loaded config from: %s", configPath)
+	printInfo("Loaded config from: %s", config.ResolvePath(configPath))
+
+	issues := cfg.Validate()
+	for _, issue := range issues {
+		printWarning("%s", issue)
+	}
+	if config.HasErrors(issues) {
+		return withCode(exitConfig, errors.New("invalid configuration - run 'ai-docs config validate' for details"))
+	}
 
 	docBranch := cfg.GetDocBranchName()
 	printInfo("Doc branch: %s", docBranch)
 	printInfo("Worktree dir: %s", cfg.DocWorktreeDir)
 
-	printStep(2, 9, "Performing checks")
-	if !utils.BranchExists(cfg.MainBranchName) {
-		return fmt.Errorf("main branch '%s' does not exist", cfg.MainBranchName)
+	git := utils.GitClient()
+	journalPath, err := git.GitPath(ctx, "ai-docs/init-journal.json")
+	if err != nil {
+		return fmt.Errorf("failed to locate init journal: %w", err)
+	}
+	journal, err := utils.LoadJournal(journalPath)
+	if err != nil {
+		return err
 	}
 
-	if utils.BranchExists(docBranch) && !force {
-		return fmt.Errorf("doc branch '%s' already exists (use --force to override)", docBranch)
+	if journal.Exists() && !resume {
+		return fmt.Errorf("a previous 'ai-docs init' was interrupted - run 'ai-docs init --resume' to continue it")
+	}
+	if resume && !journal.Exists() {
+		return fmt.Errorf("no interrupted init to resume")
 	}
 
-	if utils.PathExists(cfg.DocWorktreeDir) {
-		if force {
-			cmd := exec.Command("git", "worktree", "remove", "--force", cfg.DocWorktreeDir)
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to remove worktree %s: %w", cfg.DocWorktreeDir, err)
-			}
-		} else {
+	// A resumed init continues with the steps it started with, even if
+	// origin has changed since.
+	join, joinRecorded := false, false
+	if resume {
+		if v, ok := journal.Params["join"]; ok {
+			join, joinRecorded = v == "true", true
+		}
+	}
+	if !joinRecorded {
+		if join, err = git.RemoteBranchExists(ctx, "origin", docBranch); err != nil {
+			printWarning("Could not check origin for %s, creating a new doc branch: %v", docBranch, err)
+		}
+	}
+	steps := initSteps(ctx, cfg, docBranch, join)
+	total := len(steps) + 3
+
+	printStep(2, total, "Performing checks")
+	if !git.BranchExists(ctx, cfg.MainBranchName) {
+		return fmt.Errorf("main branch '%s' does not exist", cfg.MainBranchName)
+	}
+	if join {
+		printInfo("Found origin/%s - joining the existing doc branch", docBranch)
+	}
+
+	if !resume {
+		if git.BranchExists(ctx, docBranch) && !force {
+			return fmt.Errorf("doc branch '%s' already exists (use --force to override)", docBranch)
+		}
+
+		if utils.PathExists(cfg.DocWorktreeDir) && !force {
 			return fmt.Errorf("worktree directory '%s' already exists (use --force to override)", cfg.DocWorktreeDir)
 		}
 	}
 
-	currentBranch, err := utils.GetCurrentBranch()
-	if err != nil {
-		return fmt.Errorf("failed to get current branch: %w", err)
+	if !join {
+		redactor, _, err := loadRedactor(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		if err := scanForSecrets(ctx, cfg, redactor); err != nil {
+			return err
+		}
 	}
 
 	if dryRun {
@@ -84,127 +143,449 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	printStep(3, 9, fmt.Sprintf("Creating docs branch: %s", docBranch))
-	if utils.BranchExists(docBranch) && force {
-		printInfo("Deleting existing branch: %s", docBranch)
-		if err := utils.RunGit("", "branch", "-D", docBranch); err != nil {
-			return fmt.Errorf("failed to delete existing branch: %w", err)
+	if !resume {
+		if err := journal.Start(map[string]string{"join": strconv.FormatBool(join)}); err != nil {
+			return fmt.Errorf("failed to record progress: %w", err)
 		}
 	}
 
-	if err := utils.RunGit("", "checkout", "--orphan", docBranch); err != nil {
-		return fmt.Errorf("failed to create orphan branch: %w", err)
+	for i, step := range steps {
+		printStep(i+3, total, step.description)
+		if journal.Done(step.name) {
+			printInfo("Already completed, skipping")
+			continue
+		}
+
+		data, err := step.run()
+		if err != nil && ctx.Err() != nil {
+			// Interrupted or timed out: keep the journal so the user can
+			// resume instead of rolling back half-finished steps.
+			return fmt.Errorf("init stopped during %q, run 'ai-docs init --resume' to continue: %w", step.name, err)
+		}
+		if err != nil {
+			printWarning("Step failed, rolling back: %v", err)
+			rollbackInit(journal, steps)
+			return err
+		}
+		if err := journal.Record(step.name, data); err != nil {
+			return fmt.Errorf("failed to record progress: %w", err)
+		}
+	}
+
+	if err := journal.Remove(); err != nil {
+		printWarning("%v", err)
 	}
-	if err := utils.RunGit("", "reset"); err != nil {
-		return fmt.Errorf("failed to rest orphan branch: %w", err)
+	if backup, err := git.GitPath(ctx, initBackupDir); err == nil {
+		os.RemoveAll(backup)
 	}
 
-	// ✅ Validate that HEAD is now on the orphan branch
-	printStep(4, 9, "Validating branch switch")
-	currentBranchNow, err := utils.GetCurrentBranch()
-	if err != nil {
-		return fmt.Errorf("failed to confirm current branch after switch: %w", err)
+	if cfg.EncryptionEnabled() {
+		if err := setupDecryptedDiff(ctx, cfg); err != nil {
+			printWarning("Failed to set up decrypted diffs: %v", err)
+		}
 	}
 
-	if currentBranchNow != docBranch {
-		return fmt.Errorf("not on expected orphan branch '%s' (got '%s')", docBranch, currentBranchNow)
+	printStep(total, total, "Initialization complete")
+	printSuccess("AI docs initialized successfully!")
+	fmt.Println("\nNext steps:")
+	fmt.Println("  - Keep editing AI memory files in place; they are ignored on the main branch")
+	fmt.Println("  - Run 'ai-docs push' to commit and push changes")
+	fmt.Println("  - Run 'ai-docs pull' to get latest changes from remote")
+
+	return nil
+}
+
+// initStep is one undoable unit of work performed by init. run returns the
+// data that undo needs; both are recorded in the init journal.
+type initStep struct {
+	name        string
+	description string
+	run         func() (map[string]string, error)
+	undo        func(data map[string]string) error
+}
+
+// initSteps returns the steps for init. When join is set the doc branch is
+// adopted from origin instead of being created as a new orphan branch.
+func initSteps(ctx context.Context, cfg *config.Config, docBranch string, join bool) []initStep {
+	git := utils.GitClient()
+
+	removeExisting := initStep{
+		name:        "remove-existing",
+		description: "Preparing branch and worktree",
+		run: func() (map[string]string, error) {
+			data := map[string]string{}
+			if !force {
+				return data, nil
+			}
+
+			if utils.PathExists(cfg.DocWorktreeDir) {
+				printInfo("Removing existing worktree: %s", cfg.DocWorktreeDir)
+				if err := git.RemoveWorktree(ctx, cfg.DocWorktreeDir); err != nil {
+					if err := os.RemoveAll(cfg.DocWorktreeDir); err != nil {
+						return nil, fmt.Errorf("failed to remove worktree %s: %w", cfg.DocWorktreeDir, err)
+					}
+				}
+			}
+
+			if git.BranchExists(ctx, docBranch) {
+				sha, err := git.ResolveRef(ctx, "refs/heads/"+docBranch)
+				if err != nil {
+					return nil, err
+				}
+				printInfo("Deleting existing branch: %s", docBranch)
+				if err := git.DeleteBranch(ctx, docBranch); err != nil {
+					return nil, fmt.Errorf("failed to delete existing branch: %w", err)
+				}
+				data["branchCommit"] = sha
+			}
+			return data, nil
+		},
+		undo: func(data map[string]string) error {
+			if sha := data["branchCommit"]; sha != "" {
+				return git.CreateBranch(ctx, docBranch, sha)
+			}
+			return nil
+		},
+	}
+
+	ignoreRules := initStep{
+		name:        "ignore-rules",
+		description: "Updating ignore rules",
+		run: func() (map[string]string, error) {
+			data, err := snapshotIgnoreFiles(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if err := syncIgnoreBlock(ctx, cfg); err != nil {
+				return nil, fmt.Errorf("failed to update ignore rules: %w", err)
+			}
+			return data, nil
+		},
+		undo: restoreIgnoreFiles,
 	}
-	printSuccess("Successfully switched to orphan branch: %s", docBranch)
 
-	printStep(5, 9, "Creating initial commit")
-	for _, path := range cfg.AIAgentMemoryContextPath {
-		if utils.PathExists(path) {
-			if err := utils.RunGit("", "add", "-f", path); err != nil {
-				printWarning("Failed to stage %s: %v", path, err)
-			} else {
-				printInfo("Staged: %s", path)
+	addWorktree := initStep{
+		name:        "add-worktree",
+		description: "Adding worktree",
+		run: func() (map[string]string, error) {
+			if resume && utils.PathExists(cfg.DocWorktreeDir) {
+				printInfo("Worktree %s was added before the interruption", cfg.DocWorktreeDir)
+				return nil, nil
 			}
-		} else {
-			printInfo("Skipped (not found): %s", path)
+			if err := git.AddWorktree(ctx, cfg.DocWorktreeDir, docBranch); err != nil {
+				// worktree add can fail after registering the worktree (e.g. in a hook)
+				if utils.PathExists(cfg.DocWorktreeDir) {
+					_ = git.RemoveWorktree(ctx, cfg.DocWorktreeDir)
+				}
+				return nil, fmt.Errorf("failed to add worktree: %w", err)
+			}
+			printSuccess("Added worktree at %s", cfg.DocWorktreeDir)
+			return nil, nil
+		},
+		undo: func(data map[string]string) error {
+			return git.RemoveWorktree(ctx, cfg.DocWorktreeDir)
+		},
+	}
+
+	if join {
+		return []initStep{
+			removeExisting,
+			{
+				name:        "track-branch",
+				description: fmt.Sprintf("Tracking origin/%s", docBranch),
+				run: func() (map[string]string, error) {
+					if resume && git.BranchExists(ctx, docBranch) {
+						printInfo("Branch %s was created before the interruption", docBranch)
+						return nil, nil
+					}
+					if err := git.CreateTrackingBranch(ctx, "origin", docBranch); err != nil {
+						return nil, fmt.Errorf("failed to create tracking branch: %w", err)
+					}
+					printSuccess("Created %s tracking origin/%s", docBranch, docBranch)
+					return nil, nil
+				},
+				undo: func(data map[string]string) error {
+					return git.DeleteBranch(ctx, docBranch)
+				},
+			},
+			ignoreRules,
+			addWorktree,
+			{
+				name:        "copy-files",
+				description: "Copying files to local",
+				run: func() (map[string]string, error) {
+					// Local files replaced with --overwrite are backed up so
+					// that a rollback can put them back.
+					backup, err := git.GitPath(ctx, initBackupDir)
+					if err != nil {
+						return nil, err
+					}
+					if err := os.RemoveAll(backup); err != nil {
+						return nil, err
+					}
+					replaced := map[string]bool{}
+					var backedUp []string
+					if overwrite {
+						for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+							path := cfg.AIAgentMemoryContextPath[name]
+							if !utils.PathExists(path) || !utils.PathExists(filepath.Join(cfg.DocWorktreeDir, path)) {
+								continue
+							}
+							if err := utils.CopyPath(ctx, path, filepath.Join(backup, path)); err != nil {
+								return nil, fmt.Errorf("failed to back up %s: %w", path, err)
+							}
+							replaced[path] = true
+							backedUp = append(backedUp, path)
+						}
+					}
+
+					copied, _, err := copyToLocal(ctx, cfg, cfg.DocWorktreeDir, false)
+					data := map[string]string{"backup": backup}
+					var created []string
+					for _, path := range copied {
+						if !replaced[path] {
+							created = append(created, path)
+						}
+					}
+					data["created"] = strings.Join(created, "\n")
+					data["replaced"] = strings.Join(backedUp, "\n")
+					if err != nil {
+						// Undo the copies made before the failure right away,
+						// as the step is not recorded in the journal.
+						if err := undoCopyFiles(ctx, data); err != nil {
+							printWarning("Failed to undo copying files: %v", err)
+						}
+						return nil, err
+					}
+					return data, nil
+				},
+				undo: func(data map[string]string) error {
+					return undoCopyFiles(ctx, data)
+				},
+			},
 		}
 	}
 
-	if err := utils.RunGit("", "commit", "-m", "Initial AI docs commit", "--allow-empty"); err != nil {
-		return fmt.Errorf("failed to create commit: %w", err)
+	return []initStep{
+		removeExisting,
+		{
+			name:        "create-branch",
+			description: fmt.Sprintf("Creating docs branch: %s", docBranch),
+			run: func() (map[string]string, error) {
+				if resume && git.BranchExists(ctx, docBranch) {
+					printInfo("Branch %s was created before the interruption", docBranch)
+					return nil, nil
+				}
+
+				var paths []string
+				for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+					path := cfg.AIAgentMemoryContextPath[name]
+					if utils.PathExists(path) {
+						printInfo("Including: %s", path)
+						paths = append(paths, path)
+					} else {
+						printInfo("Skipped (not found): %s", path)
+					}
+				}
+
+				dir, err := branchSnapshot(ctx, cfg, paths)
+				if err != nil {
+					return nil, err
+				}
+				if dir != "" {
+					defer os.RemoveAll(dir)
+					if cfg.EncryptionEnabled() {
+						paths = append(paths, ".gitattributes")
+						if recipientsRecord(cfg) != nil {
+							paths = append(paths, recipientsFile)
+						}
+					}
+				}
+
+				commit, err := git.CreateOrphanBranch(ctx, docBranch, dir, paths, "Initial AI docs commit")
+				if err != nil {
+					return nil, fmt.Errorf("failed to create orphan branch: %w", err)
+				}
+				printSuccess("Created branch %s at %s", docBranch, shortCommit(commit))
+				return map[string]string{"commit": commit}, nil
+			},
+			undo: func(data map[string]string) error {
+				return git.DeleteBranch(ctx, docBranch)
+			},
+		},
+		{
+			name:        "push-branch",
+			description: "Pushing docs branch",
+			run: func() (map[string]string, error) {
+				if err := utils.PushWithRetry(ctx, git, "", docBranch, utils.PushOptions{Retries: cfg.PushRetries, Timeout: cfg.PushTimeoutDuration()}); err != nil {
+					if err := stepFailed(withCode(exitNetwork, fmt.Errorf("failed to push branch: %w", err))); err != nil {
+						return nil, err
+					}
+					return map[string]string{"pushed": "false"}, nil
+				}
+				printSuccess("Pushed branch to origin")
+				if err := git.SetUpstream(ctx, "origin", docBranch); err != nil {
+					if err := stepFailed(fmt.Errorf("failed to set upstream: %w", err)); err != nil {
+						return nil, err
+					}
+				}
+				return map[string]string{"pushed": "true"}, nil
+			},
+			undo: func(data map[string]string) error {
+				if data["pushed"] != "true" {
+					return nil
+				}
+				return git.DeleteRemoteBranch(ctx, "origin", docBranch)
+			},
+		},
+		ignoreRules,
+		addWorktree,
 	}
+}
 
-	if err := utils.PushWithRetry("", docBranch, 3); err != nil {
-		printWarning("Failed to push branch: %v", err)
-	} else {
-		printSuccess("Pushed branch to origin")
+// branchSnapshot copies paths through the push transform (redaction and
+// encryption) into a temporary directory for the initial commit, so that
+// unredacted or unencrypted content never reaches the doc branch. It returns
+// "" when no transform is configured.
+func branchSnapshot(ctx context.Context, cfg *config.Config, paths []string) (dir string, err error) {
+	redactor, mapping, err := loadRedactor(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	transform, err := pushTransform(ctx, cfg, redactor)
+	if err != nil || transform == nil {
+		return "", err
 	}
 
-	printStep(6, 9, "Returning to main branch")
-	if err := utils.RunGit("", "switch", "-f", cfg.MainBranchName); err != nil {
-		if err := utils.RunGit("", "switch", currentBranch); err != nil {
-			return fmt.Errorf("failed to return to branch: %w", err)
+	dir, err = os.MkdirTemp("", "ai-docs-init-")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	for _, path := range paths {
+		if err := utils.CopyPathWith(ctx, path, filepath.Join(dir, path), transform); err != nil {
+			return "", fmt.Errorf("failed to prepare %s: %w", path, err)
+		}
+	}
+	if mapping != nil {
+		if err := mapping.Save(); err != nil {
+			return "", fmt.Errorf("failed to save redaction mapping: %w", err)
 		}
 	}
+	if cfg.EncryptionEnabled() {
+		if err := os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte(decryptAttributes), 0644); err != nil {
+			return "", err
+		}
+		if err := recordRecipients(cfg, dir); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
 
-	printStep(7, 9, "Updating .gitignore")
-	gitignorePath := ".gitignore"
+// rollbackInit undoes the completed steps recorded in the journal in reverse
+// order and removes the journal.
+func rollbackInit(journal *utils.Journal, steps []initStep) {
+	byName := make(map[string]initStep, len(steps))
+	for _, step := range steps {
+		byName[step.name] = step
+	}
 
-	for _, pattern := range cfg.IgnorePatterns {
-		if !utils.FileContains(gitignorePath, pattern) {
-			if err := utils.AppendToFile(gitignorePath, []string{pattern}); err != nil {
-				printWarning("Failed to add pattern to .gitignore: %v", err)
-			} else {
-				printInfo("Added to .gitignore: %s", pattern)
-			}
+	for i := len(journal.Entries) - 1; i >= 0; i-- {
+		entry := journal.Entries[i]
+		step, ok := byName[entry.Step]
+		if !ok {
+			continue
+		}
+		printInfo("Undoing: %s", step.description)
+		if err := step.undo(entry.Data); err != nil {
+			printWarning("Failed to undo %q: %v", step.description, err)
 		}
 	}
 
-	if !utils.FileContains(gitignorePath, cfg.DocWorktreeDir) {
-		if err := utils.AppendToFile(gitignorePath, []string{cfg.DocWorktreeDir}); err != nil {
-			printWarning("Failed to add worktree dir to .gitignore: %v", err)
-		} else {
-			printInfo("Added to .gitignore: %s", cfg.DocWorktreeDir)
-		}
+	if err := journal.Remove(); err != nil {
+		printWarning("%v", err)
 	}
+}
 
-	printStep(8, 9, "Adding worktree")
-	if utils.PathExists(cfg.DocWorktreeDir) && force {
-		printInfo("Removing existing worktree")
-		if err := utils.RunGit("", "worktree", "remove", "-f", cfg.DocWorktreeDir); err != nil {
-			if err := os.RemoveAll(cfg.DocWorktreeDir); err != nil {
-				return fmt.Errorf("failed to remove worktree %v", err)
-			}
+// initBackupDir holds, relative to the git directory, the local files
+// replaced by init until it completes.
+const initBackupDir = "ai-docs/init-backup"
+
+// undoCopyFiles removes the files created by the copy-files step of a joining
+// init and puts back the local files it replaced from their backup.
+func undoCopyFiles(ctx context.Context, data map[string]string) error {
+	for _, path := range strings.Split(data["created"], "\n") {
+		if path == "" {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
 		}
 	}
+	for _, path := range strings.Split(data["replaced"], "\n") {
+		if path == "" {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if err := utils.CopyPath(ctx, filepath.Join(data["backup"], path), path); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", path, err)
+		}
+	}
+	return os.RemoveAll(data["backup"])
+}
 
-	if err := utils.RunGit("", "worktree", "add", cfg.DocWorktreeDir, docBranch); err != nil {
-		return fmt.Errorf("failed to add worktree: %w", err)
+// snapshotIgnoreFiles captures .gitignore and info/exclude so that
+// restoreIgnoreFiles can put them back.
+func snapshotIgnoreFiles(ctx context.Context) (map[string]string, error) {
+	excludePath, err := utils.GitClient().GitPath(ctx, "info/exclude")
+	if err != nil {
+		return nil, err
 	}
-	printSuccess("Added worktree at %s", cfg.DocWorktreeDir)
 
-	printStep(9, 9, "Initialization complete")
-	printSuccess("AI docs initialized successfully!")
-	fmt.Println("\nNext steps:")
-	fmt.Println("  - Edit AI memory files in the symlinked directories")
-	fmt.Println("  - Run 'ai-docs push' to commit and push changes")
-	fmt.Println("  - Run 'ai-docs pull' to get latest changes from remote")
+	data := map[string]string{}
+	for _, path := range []string{gitignorePath, excludePath} {
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		data[path] = string(content)
+	}
+	data["excludePath"] = excludePath
+	return data, nil
+}
 
+func restoreIgnoreFiles(data map[string]string) error {
+	for _, path := range []string{gitignorePath, data["excludePath"]} {
+		content, existed := data[path]
+		if !existed {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func createScaffoldingConfig(path string) error {
-	content := `userName: ""   # fallback git config user.name or whoami when userName is embpty 
-mainBranchName: "main"
-
-docBranchNameTemplate: "@ai-docs/{userName}"  # {userName} ↔ runtime replace
-docWorktreeDir: ".ai-docs"
-
-aIAgentMemoryContextPath:
-  Cline: "memory-bank"
-  Claude: "CLAUDE.md"
-  Gemini: "GEMINI.md"
-  Cursor: ".cursor/rules"
-
-ignorePatterns:
-  - "memory-bank"
-  - "CLAUDE.md"
-  - "GEMINI.md"
-  - ".cursor"
-`
-	return os.WriteFile(path, []byte(content), 0644)
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
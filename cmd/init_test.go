@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+const testDocBranch = "@doc/tester"
+
+// initState is what init may change in a repository.
+type initState struct {
+	status, branches, remote string
+	claude                   string
+	journal, worktree        bool
+}
+
+func (r *testRepo) initState(t *testing.T, dir string) initState {
+	t.Helper()
+	s := initState{
+		status:   r.git(t, dir, "status", "--porcelain", "--ignored"),
+		branches: r.git(t, dir, "branch", "--list"),
+		remote:   r.git(t, dir, "ls-remote", "--heads", "origin"),
+		worktree: utils.PathExists(filepath.Join(dir, ".mem")),
+		journal:  utils.PathExists(filepath.Join(dir, r.git(t, dir, "rev-parse", "--git-path", "ai-docs/init-journal.json"))),
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "CLAUDE.md")); err == nil {
+		s.claude = string(data)
+	}
+	return s
+}
+
+func TestInitRollback(t *testing.T) {
+	r := newTestRepo(t)
+	// Step 4, ignore-rules, fails after the branch was created and pushed.
+	if err := os.Mkdir(".gitignore", 0755); err != nil {
+		t.Fatal(err)
+	}
+	before := r.initState(t, r.work)
+
+	if err := runCommand(t, "init", "--yes"); err == nil {
+		t.Fatal("init succeeded")
+	}
+	if after := r.initState(t, r.work); after != before {
+		t.Errorf("got:\n%+v\nwant:\n%+v", after, before)
+	}
+}
+
+func TestInitJoinRollbackRestoresReplacedFiles(t *testing.T) {
+	r := newTestRepo(t)
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+
+	other := r.clone(t, "other")
+	t.Chdir(other)
+	writeFile(t, "CLAUDE.md", "mine\n")
+	// Copying .cursor/rules fails as .cursor is a file, after CLAUDE.md was
+	// replaced.
+	writeFile(t, ".cursor", "not a directory\n")
+	before := r.initState(t, other)
+
+	if err := runCommand(t, "init", "--yes", "--overwrite", "--strict"); err == nil {
+		t.Fatal("init succeeded")
+	}
+	if after := r.initState(t, other); after != before {
+		t.Errorf("got:\n%+v\nwant:\n%+v", after, before)
+	}
+	if got := readFile(t, ".cursor"); got != "not a directory\n" {
+		t.Errorf(".cursor = %q", got)
+	}
+	if backup := r.git(t, other, "rev-parse", "--git-path", initBackupDir); utils.PathExists(filepath.Join(other, backup)) {
+		t.Error("backup of replaced files was kept")
+	}
+}
+
+func TestInitResumeKeepsRecordedSteps(t *testing.T) {
+	r := newTestRepo(t)
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An init joining origin's doc branch is interrupted after tracking it,
+	// and the branch is deleted from origin before it is resumed.
+	other := r.clone(t, "other")
+	t.Chdir(other)
+	journal, err := utils.LoadJournal(r.git(t, other, "rev-parse", "--git-path", "ai-docs/init-journal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Start(map[string]string{"join": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	r.git(t, other, "branch", testDocBranch, "origin/"+testDocBranch)
+	for _, step := range []string{"remove-existing", "track-branch"} {
+		if err := journal.Record(step, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.git(t, r.work, "push", "-q", "origin", "--delete", testDocBranch)
+
+	if err := runCommand(t, "init", "--resume"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, "CLAUDE.md"); got != "# Claude memory\n" {
+		t.Errorf("CLAUDE.md = %q, want the copy from the joined branch", got)
+	}
+	if remote := r.git(t, other, "ls-remote", "--heads", "origin", testDocBranch); remote != "" {
+		t.Errorf("resumed init pushed a new doc branch: %s", remote)
+	}
+	if s := r.initState(t, other); s.journal || !s.worktree {
+		t.Errorf("got %+v, want a worktree and no journal", s)
+	}
+}
+
+func TestInitJoinsExistingDocBranch(t *testing.T) {
+	r := newTestRepo(t)
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+	remote := r.git(t, r.work, "ls-remote", "--heads", "origin", testDocBranch)
+
+	other := r.clone(t, "other")
+	t.Chdir(other)
+	writeFile(t, filepath.Join(".cursor", "rules", "a.mdc"), "mine\n")
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.git(t, other, "ls-remote", "--heads", "origin", testDocBranch); got != remote {
+		t.Errorf("origin doc branch changed:\ngot:  %s\nwant: %s", got, remote)
+	}
+	if got := r.git(t, other, "rev-parse", "--abbrev-ref", testDocBranch+"@{upstream}"); got != "origin/"+testDocBranch {
+		t.Errorf("upstream = %q", got)
+	}
+	if got := r.git(t, filepath.Join(other, ".mem"), "rev-parse", "--abbrev-ref", "HEAD"); got != testDocBranch {
+		t.Errorf("worktree is on %q", got)
+	}
+	if got := readFile(t, "CLAUDE.md"); got != "# Claude memory\n" {
+		t.Errorf("CLAUDE.md = %q, want the copy from the doc branch", got)
+	}
+	if got := readFile(t, filepath.Join(".cursor", "rules", "a.mdc")); got != "mine\n" {
+		t.Errorf("a.mdc = %q, want the local file kept", got)
+	}
+	if status := r.git(t, other, "status", "--porcelain"); status != "?? .gitignore" {
+		t.Errorf("status:\n%s\nwant only the new .gitignore", status)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+var keygenOutput string
+
+var keygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Generate an age key for encrypting the doc branch",
+	Long: `Generates an age X25519 identity, writes it to the configured
+encryption.identityFile (or --output) and prints the public key to add to
+encryption.recipients.`,
+	RunE: runKeygen,
+}
+
+func init() {
+	rootCmd.AddCommand(keygenCmd)
+	keygenCmd.Flags().StringVarP(&keygenOutput, "output", "o", "", "write the identity to this file")
+	keygenCmd.Flags().BoolVar(&force, "force", false, "overwrite an existing identity file")
+}
+
+func runKeygen(cmd *cobra.Command, args []string) error {
+	path := keygenOutput
+	if path == "" {
+		enc := &config.Encryption{}
+		if cfg, err := loadConfig(); err == nil && cfg.Encryption != nil {
+			enc = cfg.Encryption
+		}
+		path = enc.IdentityFilePath()
+	}
+
+	if utils.PathExists(path) && !force {
+		return fmt.Errorf("identity file %s already exists - use --force to replace it", path)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	content := fmt.Sprintf("# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), identity.Recipient(), identity)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write identity file: %w", err)
+	}
+
+	printSuccess("Wrote identity to %s", path)
+	fmt.Printf("Public key: %s\n", identity.Recipient())
+	fmt.Println("\nAdd it to your config to encrypt the doc branch:")
+	fmt.Printf("  encryption:\n    recipients:\n      - %s\n", identity.Recipient())
+	return nil
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// lockName is the lock file, kept next to the config file.
+const lockName = ".ai-docs.lock"
+
+// lockVersion is the lock file format. Newer versions are rejected.
+const lockVersion = 1
+
+// lockFile pins what pull resolved, so that every checkout of the project
+// sees the same memory.
+type lockFile struct {
+	Version int `json:"version"`
+	// DocBranch and Commit are the doc branch and its commit that the last
+	// pull copied.
+	DocBranch string `json:"docBranch,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	// Files are the sha256 hashes of the files under the agent paths at
+	// Commit, as stored in the doc branch.
+	Files map[string]string `json:"files,omitempty"`
+	// Sources are the commits the configured sources resolved to, by name.
+	Sources map[string]repoPin `json:"sources,omitempty"`
+}
+
+func (l *lockFile) empty() bool {
+	return l.Commit == "" && len(l.Files) == 0 && len(l.Sources) == 0
+}
+
+// lockPath returns where the lock file of cfg is kept.
+func lockPath(ctx context.Context, git utils.Git, cfg *config.Config) (string, error) {
+	if cfg.LockFile == config.LockFileLocal {
+		return git.GitPath(ctx, filepath.Join("ai-docs", "ai-docs.lock"))
+	}
+	return filepath.Join(filepath.Dir(config.ResolvePath(configPath)), lockName), nil
+}
+
+// commitRe matches a full SHA-1 or SHA-256 commit hash.
+var commitRe = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// loadLock reads the lock file at path. A missing file yields an empty lock.
+func loadLock(path string) (*lockFile, error) {
+	lock := &lockFile{Version: lockVersion}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if lock.Version > lockVersion {
+		return nil, fmt.Errorf("%s has version %d, this ai-docs reads up to %d - upgrade ai-docs", path, lock.Version, lockVersion)
+	}
+	if lock.Commit != "" && !commitRe.MatchString(lock.Commit) {
+		return nil, fmt.Errorf("invalid %s: commit %q is not a full commit hash", path, lock.Commit)
+	}
+	for _, name := range slices.Sorted(maps.Keys(lock.Sources)) {
+		if pin := lock.Sources[name]; !commitRe.MatchString(pin.Commit) {
+			return nil, fmt.Errorf("invalid %s: commit %q of source %s is not a full commit hash", path, pin.Commit, name)
+		}
+	}
+	return lock, nil
+}
+
+// saveLock writes lock to path unless it equals what is on disk. An empty
+// lock is not written if there is no lock file yet. It reports whether the
+// file changed.
+func saveLock(path string, lock *lockFile) (bool, error) {
+	lock.Version = lockVersion
+	if len(lock.Files) == 0 {
+		lock.Files = nil
+	}
+	if len(lock.Sources) == 0 {
+		lock.Sources = nil
+	}
+	if !utils.PathExists(path) {
+		if lock.empty() {
+			return false, nil
+		}
+	} else if old, err := loadLock(path); err == nil && reflect.DeepEqual(old, lock) {
+		return false, nil
+	}
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// lockDocBranch records commit of the doc branch and the hashes of its
+// files in lock.
+func lockDocBranch(ctx context.Context, cfg *config.Config, lock *lockFile, commit string) error {
+	files, err := branchFiles(ctx, cfg, commit)
+	if err != nil {
+		return err
+	}
+	lock.DocBranch = cfg.GetDocBranchName()
+	lock.Commit = commit
+	lock.Files = fileHashes(files)
+	return nil
+}
+
+// fileHashes returns the sha256 hash of each file, by name.
+func fileHashes(files []utils.ArchiveFile) map[string]string {
+	hashes := make(map[string]string, len(files))
+	for _, f := range files {
+		sum := sha256.Sum256(f.Data)
+		hashes[f.Name] = hex.EncodeToString(sum[:])
+	}
+	return hashes
+}
+
+// recordLock records the current commit of the doc branch in lock and
+// writes lock to path.
+func recordLock(ctx context.Context, git utils.Git, cfg *config.Config, lock *lockFile, path string) error {
+	docBranch := cfg.GetDocBranchName()
+	commit, err := git.ResolveRef(ctx, docBranch)
+	if err == nil {
+		err = lockDocBranch(ctx, cfg, lock, commit)
+	}
+	if err != nil {
+		printWarning("Could not record the commit of %s in %s: %v", docBranch, path, err)
+	}
+
+	changed, err := saveLock(path, lock)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if changed {
+		printSuccess("Updated %s", path)
+	}
+	return nil
+}
+
+// checkoutLocked writes the files of the doc branch at the commit recorded
+// in lock to a new temporary directory, after checking them against the
+// hashes in lock. If the commit is missing, the doc branch is fetched from
+// origin first.
+func checkoutLocked(ctx context.Context, git utils.Git, cfg *config.Config, lock *lockFile, path string) (string, error) {
+	if lock.Commit == "" {
+		return "", fmt.Errorf("%s records no doc branch commit - run 'ai-docs pull' without --locked first", path)
+	}
+	branch := lock.DocBranch
+	if branch == "" {
+		branch = cfg.GetDocBranchName()
+	}
+
+	files, err := branchFiles(ctx, cfg, lock.Commit)
+	if err != nil {
+		printInfo("Fetching origin/%s for %s", branch, shortCommit(lock.Commit))
+		if ferr := git.Fetch(ctx, "origin", branch); ferr != nil {
+			return "", fmt.Errorf("locked commit %s is not available and origin/%s could not be fetched: %w", shortCommit(lock.Commit), branch, ferr)
+		}
+		if files, err = branchFiles(ctx, cfg, lock.Commit); err != nil {
+			return "", fmt.Errorf("locked commit %s is not on origin/%s - push it, or pull without --locked: %w", shortCommit(lock.Commit), branch, err)
+		}
+	}
+
+	if err := lock.verify(files, path); err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "ai-docs-locked-*")
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		dst := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		if err := os.WriteFile(dst, f.Data, f.Mode); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	printSuccess("Checked out %s at %s (%d files)", branch, shortCommit(lock.Commit), len(files))
+	return dir, nil
+}
+
+// verify checks files, read at lock.Commit, against the hashes in lock,
+// which was read from path.
+func (l *lockFile) verify(files []utils.ArchiveFile, path string) error {
+	hashes := fileHashes(files)
+	for _, name := range sortedKeys(l.Files) {
+		if hashes[name] != l.Files[name] {
+			return fmt.Errorf("%s at %s does not match the hash in %s", name, shortCommit(l.Commit), path)
+		}
+	}
+	for _, name := range sortedKeys(hashes) {
+		if _, ok := l.Files[name]; !ok {
+			return fmt.Errorf("%s at %s is not listed in %s - was the config changed since it was written?", name, shortCommit(l.Commit), path)
+		}
+	}
+	return nil
+}
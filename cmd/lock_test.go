@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+const testCommit = "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d"
+
+func TestLoadLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "missing file"},
+		{name: "empty lock", data: `{"version": 1}`},
+		{name: "full commit", data: `{"version": 1, "commit": "` + testCommit + `"}`},
+		{name: "sha256 commit", data: `{"version": 1, "commit": "` + testCommit + testCommit[:24] + `"}`},
+		{name: "source pin", data: `{"version": 1, "sources": {"org": {"url": "u", "commit": "` + testCommit + `"}}}`},
+		{name: "invalid json", data: `{`, wantErr: "invalid"},
+		{name: "newer version", data: `{"version": 2}`, wantErr: "upgrade ai-docs"},
+		{name: "short commit", data: `{"version": 1, "commit": "1a2b"}`, wantErr: "not a full commit hash"},
+		{name: "uppercase commit", data: `{"version": 1, "commit": "` + strings.ToUpper(testCommit) + `"}`, wantErr: "not a full commit hash"},
+		{name: "short source pin", data: `{"version": 1, "sources": {"org": {"url": "u", "commit": "abc"}}}`, wantErr: "of source org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), lockName)
+			if tt.data != "" {
+				if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lock, err := loadLock(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lock.Version != lockVersion {
+				t.Errorf("Version = %d", lock.Version)
+			}
+		})
+	}
+}
+
+func TestSaveLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), lockName)
+	if changed, err := saveLock(path, &lockFile{}); err != nil || changed || utils.PathExists(path) {
+		t.Fatalf("empty lock: changed %v, err %v, written %v", changed, err, utils.PathExists(path))
+	}
+
+	lock := &lockFile{DocBranch: "@doc/alice", Commit: testCommit, Files: map[string]string{"CLAUDE.md": "h"}}
+	if changed, err := saveLock(path, lock); err != nil || !changed {
+		t.Fatalf("changed %v, err %v", changed, err)
+	}
+	loaded, err := loadLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, lock) {
+		t.Errorf("loaded %+v, want %+v", loaded, lock)
+	}
+	if changed, err := saveLock(path, loaded); err != nil || changed {
+		t.Errorf("unchanged lock: changed %v, err %v", changed, err)
+	}
+}
+
+func TestLockVerify(t *testing.T) {
+	files := []utils.ArchiveFile{
+		{Name: "CLAUDE.md", Data: []byte("a\n")},
+		{Name: ".cursor/rules/b.mdc", Data: []byte("b\n")},
+	}
+	hashes := fileHashes(files)
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{name: "match", files: hashes},
+		{name: "changed file", files: map[string]string{"CLAUDE.md": "0", ".cursor/rules/b.mdc": hashes[".cursor/rules/b.mdc"]}, wantErr: "does not match"},
+		{name: "missing file", files: map[string]string{"CLAUDE.md": hashes["CLAUDE.md"], "GEMINI.md": "x", ".cursor/rules/b.mdc": hashes[".cursor/rules/b.mdc"]}, wantErr: "GEMINI.md"},
+		{name: "unlisted file", files: map[string]string{"CLAUDE.md": hashes["CLAUDE.md"]}, wantErr: "is not listed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock := &lockFile{Commit: testCommit, Files: tt.files}
+			err := lock.verify(files, lockName)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShortCommit(t *testing.T) {
+	tests := []struct{ commit, want string }{
+		{testCommit, "1a2b3c4"},
+		{"1a2b3c4", "1a2b3c4"},
+		{"abc", "abc"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := shortCommit(tt.commit); got != tt.want {
+			t.Errorf("shortCommit(%q) = %q, want %q", tt.commit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readLine reads a single trimmed line from stdin. EOF is treated as an empty
+// answer so that prompts fall back to their defaults when stdin is closed.
+// It returns early with ctx's error when ctx is cancelled.
+func readLine(ctx context.Context) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := stdinReader.ReadString('\n')
+		ch <- result{line, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil && r.err != io.EOF {
+			return "", fmt.Errorf("failed to read response: %w", r.err)
+		}
+		return strings.TrimSpace(r.line), nil
+	case <-ctx.Done():
+		fmt.Println()
+		return "", ctx.Err()
+	}
+}
+
+func promptString(ctx context.Context, question, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	answer, err := readLine(ctx)
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return defaultValue, nil
+	}
+	return answer, nil
+}
+
+func promptYesNo(ctx context.Context, question string, defaultYes bool) (bool, error) {
+	hint := "y/N"
+	if defaultYes {
+		hint = "Y/n"
+	}
+	fmt.Printf("%s (%s): ", question, hint)
+
+	answer, err := readLine(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func promptChoice(ctx context.Context, question string, choices []string, defaultValue string) (string, error) {
+	for {
+		answer, err := promptString(ctx, fmt.Sprintf("%s (%s)", question, strings.Join(choices, "/")), defaultValue)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range choices {
+			if strings.EqualFold(answer, c) {
+				return c, nil
+			}
+		}
+		printWarning("Please choose one of: %s", strings.Join(choices, ", "))
+	}
+}
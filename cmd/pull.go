@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -10,7 +13,10 @@ import (
 )
 
 var (
-	overwrite bool
+	overwrite     bool
+	fromBundle    string
+	updateSources bool
+	pullLocked    bool
 )
 
 var pullCmd = &cobra.Command{
@@ -23,30 +29,47 @@ var pullCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(pullCmd)
 	pullCmd.Flags().BoolVar(&overwrite, "overwrite", false, "overwrite local files without warning")
+	pullCmd.Flags().StringVar(&fromBundle, "from-bundle", "", "update the doc branch from this git bundle file instead of origin")
+	pullCmd.Flags().BoolVar(&updateSources, "update-sources", false, "move sources to the latest commit of their ref instead of the one in "+lockName)
+	pullCmd.Flags().BoolVar(&pullLocked, "locked", false, "copy the doc branch commit and source commits recorded in "+lockName+" instead of the latest ones")
 }
 
 func runPull(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if !utils.IsGitRepo() {
-		return fmt.Errorf("not a git repository")
+		return errNotRepo
+	}
+	if pullLocked && (fromBundle != "" || updateSources) {
+		return fmt.Errorf("--locked cannot be combined with --from-bundle or --update-sources")
 	}
 
-	printStep(1, 5, "Loading configuration")
-	cfg, err := config.LoadConfig(configPath)
+	printStep(1, 6, "Loading configuration")
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	git := utils.GitClient()
 	docBranch := cfg.GetDocBranchName()
 	printInfo("Doc branch: %s", docBranch)
 	printInfo("Worktree dir: %s", cfg.DocWorktreeDir)
 
-	printStep(2, 5, "Validating worktree")
+	printStep(2, 6, "Validating worktree")
 	if !utils.PathExists(cfg.DocWorktreeDir) {
-		return fmt.Errorf("worktree directory '%s' does not exist - run 'ai-docs init' first", cfg.DocWorktreeDir)
+		return notInitialized("worktree directory '%s' does not exist", cfg.DocWorktreeDir)
 	}
 
-	if !utils.BranchExists(docBranch) {
-		return fmt.Errorf("doc branch '%s' does not exist - run 'ai-docs init' first", docBranch)
+	if !git.BranchExists(ctx, docBranch) {
+		return notInitialized("doc branch '%s' does not exist", docBranch)
+	}
+
+	lockFilePath, err := lockPath(ctx, git, cfg)
+	if err != nil {
+		return err
+	}
+	lock, err := loadLock(lockFilePath)
+	if err != nil {
+		return err
 	}
 
 	if dryRun {
@@ -54,51 +77,252 @@ func runPull(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	printStep(3, 5, "Pulling from remote")
-	printInfo("Pulling latest changes from origin/%s", docBranch)
+	root := cfg.DocWorktreeDir
+	switch {
+	case pullLocked:
+		printStep(3, 6, "Checking out the locked commit")
+		if root, err = checkoutLocked(ctx, git, cfg, lock, lockFilePath); err != nil {
+			return err
+		}
+		defer os.RemoveAll(root)
+	case fromBundle != "":
+		printStep(3, 6, "Pulling from bundle")
+		if err := pullBundle(ctx, git, cfg, fromBundle); err != nil {
+			return err
+		}
+	default:
+		printStep(3, 6, "Pulling from remote")
+		printInfo("Pulling latest changes from origin/%s", docBranch)
+
+		err = git.Pull(ctx, cfg.DocWorktreeDir)
+		switch {
+		case err == nil:
+			printSuccess("Successfully pulled latest changes")
+		case errors.Is(err, utils.ErrConflict):
+			return fmt.Errorf("could not rebase %s onto origin/%s - run 'git -C %s rebase origin/%s', resolve the conflicts and run 'ai-docs pull' again: %w", docBranch, docBranch, cfg.DocWorktreeDir, docBranch, err)
+		case errors.Is(err, utils.ErrNoUpstream):
+			printWarning("Branch %s has no upstream - run 'ai-docs doctor --fix' to set it", docBranch)
+		case errors.Is(err, utils.ErrNotFound):
+			printInfo("origin/%s does not exist yet - push first to create it", docBranch)
+		case errors.Is(err, utils.ErrNetwork), errors.Is(err, utils.ErrAuthFailed):
+			if err := stepFailed(withCode(exitNetwork, fmt.Errorf("could not reach origin, using the local copy: %w", err))); err != nil {
+				return err
+			}
+		default:
+			if err := stepFailed(fmt.Errorf("pull failed: %w", err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	printStep(4, 6, "Copying files to local")
+	if err := syncIgnoreBlock(ctx, cfg); err != nil {
+		if err := stepFailed(fmt.Errorf("failed to update ignore rules: %w", err)); err != nil {
+			return err
+		}
+	}
+	if cfg.EncryptionEnabled() {
+		if err := setupDecryptedDiff(ctx, cfg); err != nil {
+			if err := stepFailed(fmt.Errorf("failed to set up decrypted diffs: %w", err)); err != nil {
+				return err
+			}
+		}
+	}
+
+	copied, skippedCount, err := copyToLocal(ctx, cfg, root, false)
+	if err != nil {
+		return fmt.Errorf("failed to copy files: %w", err)
+	}
+	copiedCount := len(copied)
+
+	printStep(5, 6, "Merging shared sources")
+	merged, err := pullSources(ctx, git, cfg, lock)
+	if err != nil {
+		return err
+	}
+	if !pullLocked {
+		if err := recordLock(ctx, git, cfg, lock, lockFilePath); err != nil {
+			return err
+		}
+	}
+
+	printStep(6, 6, "Pull complete")
+	printInfo("Files copied: %d, skipped: %d", copiedCount, skippedCount)
+	if len(cfg.Sources) > 0 {
+		printInfo("Files updated from sources: %d", len(merged))
+	}
+
+	if skippedCount > 0 && !overwrite && !pullLocked {
+		fmt.Println("\nUse --overwrite flag to replace existing local files")
+	}
+
+	return nil
+}
+
+// pullSources fetches the configured sources and merges them into the agent
+// paths, recording the commits they resolved to in lock. With --locked,
+// every source must be pinned in lock.
+func pullSources(ctx context.Context, git utils.Git, cfg *config.Config, lock *lockFile) ([]string, error) {
+	if len(cfg.Sources) == 0 {
+		printInfo("No sources configured")
+		if len(lock.Sources) > 0 {
+			// Drop what removed sources merged.
+			if _, err := mergeSources(ctx, cfg, nil); err != nil {
+				return nil, err
+			}
+			lock.Sources = nil
+		}
+		return nil, nil
+	}
+	if pullLocked {
+		for _, s := range cfg.Sources {
+			if pin, ok := lock.Sources[s.Name]; !ok || pin.URL != s.URL || pin.Ref != s.Ref {
+				return nil, fmt.Errorf("source %s is not pinned in the lock file - run 'ai-docs pull' without --locked to pin it", s.Name)
+			}
+		}
+	}
+
+	checkouts, err := fetchSources(ctx, git, cfg, lock, updateSources)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeSources(ctx, cfg, checkouts)
+	for _, p := range merged {
+		printSuccess("Updated from sources: %s", p)
+	}
+	return merged, err
+}
+
+// pullBundle fetches the doc branch from a git bundle into bundleRef and
+// rebases local commits onto it.
+func pullBundle(ctx context.Context, git utils.Git, cfg *config.Config, file string) error {
+	docBranch := cfg.GetDocBranchName()
+	heads, err := git.BundleHeads(ctx, file)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle %s: %w", file, err)
+	}
+	ref := "refs/heads/" + docBranch
+	if _, ok := heads[ref]; !ok {
+		if len(heads) != 1 {
+			return fmt.Errorf("bundle %s does not contain %s", file, ref)
+		}
+		for head := range heads {
+			ref = head
+		}
+		printWarning("Bundle has no %s, using %s", docBranch, ref)
+	}
+
+	printInfo("Fetching %s from %s", ref, file)
+	tracking := bundleRef(docBranch)
+	if err := git.FetchBundle(ctx, file, ref, tracking); err != nil {
+		if errors.Is(err, utils.ErrNotFound) {
+			return fmt.Errorf("bundle %s builds on commits this repository does not have - ask for one written with 'ai-docs push --to-bundle <file> --full': %w", file, err)
+		}
+		return fmt.Errorf("failed to fetch bundle: %w", err)
+	}
+
+	err = git.Rebase(ctx, cfg.DocWorktreeDir, tracking)
+	switch {
+	case err == nil:
+		printSuccess("Updated %s from %s", docBranch, file)
+	case errors.Is(err, utils.ErrConflict):
+		return fmt.Errorf("could not rebase %s onto the bundle - resolve it in %s and run 'ai-docs pull --from-bundle' again: %w", docBranch, cfg.DocWorktreeDir, err)
+	default:
+		return fmt.Errorf("failed to update %s from the bundle: %w", docBranch, err)
+	}
+	return nil
+}
 
-	if err := utils.RunGit(cfg.DocWorktreeDir, "pull", "--quiet"); err != nil {
-		printWarning("Pull failed (may be normal for new branches): %v", err)
-	} else {
-		printSuccess("Successfully pulled latest changes")
+// copyToLocal copies every agent path from root, a checkout of the doc
+// branch, into the working tree. Existing local files are kept unless
+// --overwrite is set; they are reported unless restoring, where keeping
+// them is the normal case. With --locked they are always replaced. It returns the paths that were copied and the
+// number that were skipped, and stops with an error if ctx is cancelled.
+func copyToLocal(ctx context.Context, cfg *config.Config, root string, restoring bool) (copied []string, skipped int, err error) {
+	transform, err := pullTransform(ctx, cfg, root)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	printStep(4, 5, "Copying files to local")
-	copiedCount := 0
-	skippedCount := 0
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		if err := ctx.Err(); err != nil {
+			return copied, skipped, err
+		}
 
-	for _, path := range cfg.AIAgentMemoryContextPath {
-		src := filepath.Join(cfg.DocWorktreeDir, path)
+		path := cfg.AIAgentMemoryContextPath[name]
+		src := filepath.Join(root, path)
 		dst := filepath.Join(".", path)
 
 		if !utils.PathExists(src) {
 			printInfo("Remote file does not exist: %s (skipping)", path)
-			skippedCount++
+			skipped++
 			continue
 		}
 
+		// With --locked the local copy is replaced, so that it matches the
+		// locked commit exactly, including files it no longer has.
+		if pullLocked && utils.PathExists(dst) {
+			printInfo("Replacing local %s with the locked copy", dst)
+			if err := os.RemoveAll(dst); err != nil {
+				return copied, skipped, err
+			}
+		}
+
 		// Check if local file exists and warn user
 		if utils.PathExists(dst) && !overwrite {
-			printWarning("Local file exists: %s (use --overwrite to replace)", dst)
-			skippedCount++
+			if restoring {
+				printInfo("Local file exists: %s (keeping it)", dst)
+			} else if err := fileWarning("Local file exists: %s (use --overwrite to replace)", dst); err != nil {
+				return copied, skipped, err
+			}
+			skipped++
 			continue
 		}
 
-		if err := utils.CopyPath(src, dst); err != nil {
-			printWarning("Failed to copy %s → %s: %v", src, dst, err)
-			skippedCount++
+		if err := utils.CopyPathWith(ctx, src, dst, transform); err != nil {
+			if ctx.Err() != nil {
+				return copied, skipped, err
+			}
+			if errors.Is(err, utils.ErrNoIdentity) {
+				return copied, skipped, decryptError(cfg, err)
+			}
+			if err := stepFailed(fmt.Errorf("failed to copy %s → %s: %w", src, dst, err)); err != nil {
+				return copied, skipped, err
+			}
+			skipped++
 		} else {
 			printSuccess("Copied: %s", path)
-			copiedCount++
+			copied = append(copied, path)
 		}
 	}
 
-	printStep(5, 5, "Pull complete")
-	printInfo("Files copied: %d, skipped: %d", copiedCount, skippedCount)
+	return copied, skipped, nil
+}
 
-	if skippedCount > 0 && !overwrite {
-		fmt.Println("\nUse --overwrite flag to replace existing local files")
+// pullTransform returns the transform applied to files copied from the doc
+// branch: decryption, expansion of include directives, then expansion of
+// redaction placeholders if redact.expandOnPull is set.
+func pullTransform(ctx context.Context, cfg *config.Config, root string) (utils.Transform, error) {
+	var decryptFn, expandFn utils.Transform
+
+	enc, err := loadEncryptor(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		decryptFn = enc.Decrypt
 	}
 
-	return nil
+	if cfg.Redact != nil && cfg.Redact.ExpandOnPull {
+		redactor, _, err := loadRedactor(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if redactor != nil {
+			expandFn = redactor.Expand
+		}
+	}
+
+	includeFn := newIncludeResolver(cfg, root, decryptFn).expand(ctx)
+	return utils.ChainTransforms(decryptFn, includeFn, expandFn), nil
 }
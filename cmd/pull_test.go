@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/trknhr/ai-docs/utils"
+)
+
+func TestPullLockedReplacesLocalFiles(t *testing.T) {
+	newTestRepo(t)
+	if err := runCommand(t, "init", "--yes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCommand(t, "pull"); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.PathExists(lockName) {
+		t.Fatal("pull did not write the lock file")
+	}
+
+	writeFile(t, "CLAUDE.md", "stale\n")
+	writeFile(t, filepath.Join(".cursor", "rules", "extra.mdc"), "not in the lock\n")
+	if err := runCommand(t, "pull", "--locked"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, "CLAUDE.md"); got != "# Claude memory\n" {
+		t.Errorf("CLAUDE.md = %q, want the locked copy", got)
+	}
+	if got := readFile(t, filepath.Join(".cursor", "rules", "a.mdc")); got != "rule a\n" {
+		t.Errorf("a.mdc = %q, want the locked copy", got)
+	}
+	if utils.PathExists(filepath.Join(".cursor", "rules", "extra.mdc")) {
+		t.Error("a file that is not in the locked commit was kept")
+	}
+
+	// Without --locked, local files are kept.
+	writeFile(t, "CLAUDE.md", "mine\n")
+	if err := runCommand(t, "pull"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, "CLAUDE.md"); got != "mine\n" {
+		t.Errorf("plain pull replaced CLAUDE.md: %q", got)
+	}
+}
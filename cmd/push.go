@@ -1,12 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/include"
+	"github.com/trknhr/ai-docs/redact"
+	"github.com/trknhr/ai-docs/secrets"
 	"github.com/trknhr/ai-docs/utils"
 )
 
@@ -17,32 +25,62 @@ var pushCmd = &cobra.Command{
 	RunE:  runPush,
 }
 
+var (
+	allowSecrets bool
+	toBundle     string
+	fullBundle   bool
+)
+
 func init() {
 	rootCmd.AddCommand(pushCmd)
+	pushCmd.Flags().BoolVar(&allowSecrets, "allow-secrets", false, "push even if possible secrets are found (asks for confirmation)")
+	pushCmd.Flags().StringVar(&toBundle, "to-bundle", "", "write the doc branch to this git bundle file instead of pushing to origin")
+	pushCmd.Flags().BoolVar(&fullBundle, "full", false, "with --to-bundle, include the whole history instead of only new commits")
 }
 
 func runPush(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
 	if !utils.IsGitRepo() {
-		return fmt.Errorf("not a git repository")
+		return errNotRepo
 	}
 
-	printStep(1, 6, "Loading configuration")
-	cfg, err := config.LoadConfig(configPath)
+	printStep(1, 8, "Loading configuration")
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	git := utils.GitClient()
 	docBranch := cfg.GetDocBranchName()
 	printInfo("Doc branch: %s", docBranch)
 	printInfo("Worktree dir: %s", cfg.DocWorktreeDir)
 
-	printStep(2, 6, "Validating worktree")
+	printStep(2, 8, "Validating worktree")
 	if !utils.PathExists(cfg.DocWorktreeDir) {
-		return fmt.Errorf("worktree directory '%s' does not exist - run 'ai-docs init' first", cfg.DocWorktreeDir)
+		return notInitialized("worktree directory '%s' does not exist", cfg.DocWorktreeDir)
+	}
+
+	if !git.BranchExists(ctx, docBranch) {
+		return notInitialized("doc branch '%s' does not exist", docBranch)
+	}
+
+	printStep(3, 8, "Deriving agent files")
+	if cfg.Sync != nil && cfg.Sync.Derive != nil {
+		if err := deriveAgents(cfg); err != nil {
+			return fmt.Errorf("failed to derive agent files: %w", err)
+		}
+	} else {
+		printInfo("No sync.derive config")
 	}
+	checkGenerated(cfg)
 
-	if !utils.BranchExists(docBranch) {
-		return fmt.Errorf("doc branch '%s' does not exist - run 'ai-docs init' first", docBranch)
+	printStep(4, 8, "Scanning for secrets")
+	redactor, mapping, err := loadRedactor(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if err := scanForSecrets(ctx, cfg, redactor); err != nil {
+		return err
 	}
 
 	if dryRun {
@@ -50,11 +88,27 @@ func runPush(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	printStep(3, 6, "Copying files to worktree")
+	printStep(5, 8, "Copying files to worktree")
+	transform, err := pushTransform(ctx, cfg, redactor)
+	if err != nil {
+		return err
+	}
+	if cfg.EncryptionEnabled() {
+		if err := setupDecryptedDiff(ctx, cfg); err != nil {
+			if err := stepFailed(fmt.Errorf("failed to set up decrypted diffs: %w", err)); err != nil {
+				return err
+			}
+		}
+	}
+
 	copiedCount := 0
 	skippedCount := 0
+	copyFailed := false
 
 	for _, path := range cfg.AIAgentMemoryContextPath {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		src := filepath.Join(".", path)
 		dst := filepath.Join(cfg.DocWorktreeDir, path)
 
@@ -64,9 +118,15 @@ func runPush(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		if err := utils.CopyPath(src, dst); err != nil {
-			printWarning("Failed to copy %s → %s: %v", src, dst, err)
+		if err := utils.CopyPathWith(ctx, src, dst, transform); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("failed to copy %s: %w", path, err)
+			}
+			if err := stepFailed(fmt.Errorf("failed to copy %s → %s: %w", src, dst, err)); err != nil {
+				return err
+			}
 			skippedCount++
+			copyFailed = true
 		} else {
 			printSuccess("Copied: %s", path)
 			copiedCount++
@@ -74,31 +134,263 @@ func runPush(cmd *cobra.Command, args []string) error {
 	}
 
 	printInfo("Files copied: %d, skipped: %d", copiedCount, skippedCount)
+	// A file that failed to copy may still be encrypted to the old
+	// recipients; keep the old record so the next push re-encrypts it.
+	if !copyFailed {
+		if err := recordRecipients(cfg, cfg.DocWorktreeDir); err != nil {
+			return fmt.Errorf("failed to record encryption recipients: %w", err)
+		}
+	}
+	if mapping != nil {
+		if err := mapping.Save(); err != nil {
+			return fmt.Errorf("failed to save redaction mapping: %w", err)
+		}
+	}
 
-	printStep(4, 6, "Staging changes")
-	if err := utils.RunGit(cfg.DocWorktreeDir, "add", "-A"); err != nil {
+	printStep(6, 8, "Staging changes")
+	if err := git.StageAll(ctx, cfg.DocWorktreeDir); err != nil {
 		return fmt.Errorf("failed to stage changes: %w", err)
 	}
 
-	if !utils.HasUncommittedChanges(cfg.DocWorktreeDir) {
+	changed, err := git.HasChanges(ctx, cfg.DocWorktreeDir)
+	if err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	}
+	if !changed && toBundle == "" {
+		// A commit from an earlier push may not have reached origin.
+		ahead, err := unpushedCommits(ctx, git, docBranch)
+		if err != nil {
+			return fmt.Errorf("failed to compare %s with its upstream: %w", docBranch, err)
+		}
+		if ahead == 0 {
+			printInfo("No changes to commit")
+			return nil
+		}
+		printInfo("%d commit(s) not pushed yet", ahead)
+	}
+
+	printStep(7, 8, "Creating commit")
+	if changed {
+		timestamp := time.Now().Format("2006-01-02_15:04:05")
+		commitMsg := fmt.Sprintf("Update AI docs %s", timestamp)
+
+		if err := git.Commit(ctx, cfg.DocWorktreeDir, commitMsg); err != nil {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+		printSuccess("Created commit: %s", commitMsg)
+	} else {
 		printInfo("No changes to commit")
+	}
+
+	if toBundle != "" {
+		printStep(8, 8, "Writing bundle")
+		return writeBundle(ctx, git, docBranch, toBundle)
+	}
+
+	printStep(8, 8, "Pushing to remote")
+	opts := utils.PushOptions{
+		Retries: cfg.PushRetries,
+		Timeout: cfg.PushTimeoutDuration(),
+		Rebase:  true,
+	}
+	if err := utils.PushWithRetry(ctx, git, cfg.DocWorktreeDir, docBranch, opts); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrConflict):
+			return fmt.Errorf("your changes conflict with origin/%s - run 'git -C %s rebase origin/%s', resolve the conflicts and push again: %w", docBranch, cfg.DocWorktreeDir, docBranch, err)
+		case errors.Is(err, utils.ErrNonFastForward):
+			return fmt.Errorf("origin/%s has changes you do not have - run 'ai-docs pull' first: %w", docBranch, err)
+		case errors.Is(err, utils.ErrAuthFailed):
+			return fmt.Errorf("authentication with origin failed - check your credentials: %w", err)
+		}
+		return withCode(exitNetwork, fmt.Errorf("failed to push: %w", err))
+	}
+	printSuccess("Pushed changes to origin/%s", docBranch)
+
+	return nil
+}
+
+// unpushedCommits returns the number of commits of branch that its upstream
+// does not have. Without an upstream, or before it has been fetched, branch
+// counts as one commit ahead.
+func unpushedCommits(ctx context.Context, git utils.Git, branch string) (int, error) {
+	upstream, err := git.Upstream(ctx, branch)
+	if errors.Is(err, utils.ErrNoUpstream) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := git.ResolveRef(ctx, upstream); err != nil {
+		return 1, nil
+	}
+	return git.Ahead(ctx, branch, upstream)
+}
+
+// bundleRef returns the ref that records the last commit of branch received
+// from a bundle. Commits up to it are left out of bundles written by push,
+// since the other side has them already.
+func bundleRef(branch string) string {
+	return "refs/remotes/bundle/" + branch
+}
+
+// writeBundle writes branch to the git bundle file, incrementally on top of
+// the last commit received from a bundle unless --full is set.
+func writeBundle(ctx context.Context, git utils.Git, branch, file string) error {
+	var exclude []string
+	if !fullBundle {
+		if sha, err := git.ResolveRef(ctx, bundleRef(branch)); err == nil {
+			printInfo("Leaving out commits up to %s, received from the last bundle", sha[:7])
+			exclude = append(exclude, sha)
+		}
+	}
+
+	if err := git.CreateBundle(ctx, file, branch, exclude); err != nil {
+		if errors.Is(err, utils.ErrUpToDate) {
+			printWarning("The other side already has every commit of %s - no bundle written (use --full to write one anyway)", branch)
+			return nil
+		}
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	printSuccess("Wrote %s to %s", branch, file)
+	return nil
+}
+
+// pushTransform returns the transform applied to files copied to the doc
+// branch: removal of source and included text, redaction, then encryption.
+func pushTransform(ctx context.Context, cfg *config.Config, redactor *redact.Redactor) (utils.Transform, error) {
+	var redactFn, encryptFn utils.Transform
+	if redactor != nil {
+		redactFn = redactor.Redact
+	}
+
+	enc, err := loadEncryptor(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		if recipientsChanged(cfg, cfg.DocWorktreeDir) {
+			printInfo("Encryption recipients changed: re-encrypting every file")
+			enc.Rekey()
+		} else if !enc.CanDecrypt() {
+			printWarning("No identity at %s: every file is re-encrypted on push", cfg.Encryption.IdentityFilePath())
+		}
+		encryptFn = enc.Encrypt
+	}
+
+	return utils.ChainTransforms(stripSources, collapseIncludes, redactFn, encryptFn), nil
+}
+
+// scanForSecrets scans the agent paths for credentials, after redaction if
+// redactor is set. Findings stop the push unless --allow-secrets is given
+// and the user confirms.
+func scanForSecrets(ctx context.Context, cfg *config.Config, redactor *redact.Redactor) error {
+	scanner, err := secretScanner(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid secretScan config: %w", err)
+	}
+	if scanner == nil {
+		printInfo("Secret scan disabled")
 		return nil
 	}
 
-	printStep(5, 6, "Creating commit")
-	timestamp := time.Now().Format("2006-01-02_15:04:05")
-	commitMsg := fmt.Sprintf("Update AI docs %s", timestamp)
+	var paths []string
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		paths = append(paths, cfg.AIAgentMemoryContextPath[name])
+	}
+	// Scan what is pushed: without source and included text and after
+	// redaction.
+	read := func(file string) ([]byte, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = stripSources(file, data); err != nil {
+			if errors.Is(err, utils.ErrSkipFile) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		data = include.Collapse(data)
+		if redactor != nil {
+			return redactor.Redact(file, data)
+		}
+		return data, nil
+	}
+	findings, err := scanner.ScanPaths(ctx, paths, read)
+	if err != nil {
+		return err
+	}
+	return checkFindings(ctx, findings, "pushed to origin")
+}
 
-	if err := utils.RunGit(cfg.DocWorktreeDir, "commit", "-m", commitMsg); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+// checkFindings prints secret scan findings. They stop the command unless
+// --allow-secrets is given and the user confirms that the values may be
+// published to dest.
+func checkFindings(ctx context.Context, findings []secrets.Finding, dest string) error {
+	if len(findings) == 0 {
+		printSuccess("No secrets found")
+		return nil
 	}
-	printSuccess("Created commit: %s", commitMsg)
 
-	printStep(6, 6, "Pushing to remote")
-	if err := utils.PushWithRetry(cfg.DocWorktreeDir, docBranch, 3); err != nil {
-		return fmt.Errorf("failed to push: %w", err)
+	for _, f := range findings {
+		color.Red("%s", f)
+	}
+	if !allowSecrets {
+		return fmt.Errorf("found %d possible secret(s) - remove them, mark the line with '%s', add a secretScan.allowlist entry, or rerun with --allow-secrets", len(findings), secrets.AllowComment)
 	}
-	printSuccess("Pushed changes to origin/%s", docBranch)
 
+	printWarning("--allow-secrets is set: these values will be %s", dest)
+	confirmed, err := promptYesNo(ctx, fmt.Sprintf("Continue with %d possible secret(s)?", len(findings)), false)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return fmt.Errorf("cancelled: %d possible secret(s) found", len(findings))
+	}
 	return nil
 }
+
+// secretScanner returns the scanner for the built-in rules plus the rules and
+// allowlist in SecretScan, or nil if the scan is disabled.
+func secretScanner(cfg *config.Config) (*secrets.Scanner, error) {
+	scan := cfg.SecretScan
+	if scan == nil {
+		scan = &config.SecretScan{}
+	}
+	if scan.Disabled {
+		return nil, nil
+	}
+
+	rules := secrets.DefaultRules()
+	for _, r := range scan.Rules {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("secret rule %q: invalid regex: %w", r.ID, err)
+		}
+		if r.SecretGroup > re.NumSubexp() {
+			return nil, fmt.Errorf("secret rule %q: secretGroup %d but the regex has %d group(s)", r.ID, r.SecretGroup, re.NumSubexp())
+		}
+		description := r.Description
+		if description == "" {
+			description = r.ID
+		}
+		rules = append(rules, secrets.Rule{
+			ID:          r.ID,
+			Description: description,
+			Regex:       re,
+			SecretGroup: r.SecretGroup,
+			Entropy:     r.Entropy,
+		})
+	}
+
+	allow := secrets.Allowlist{Paths: scan.Allowlist.Paths, Rules: scan.Allowlist.Rules}
+	for _, expr := range scan.Allowlist.Regexes {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("secret allowlist regex %q: %w", expr, err)
+		}
+		allow.Regexes = append(allow.Regexes, re)
+	}
+
+	return secrets.NewScanner(rules, allow), nil
+}
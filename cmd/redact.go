@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/redact"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// loadRedactor returns the Redactor for cfg together with its mapping, or
+// nil if no redaction rules are configured. Callers that redact must Save
+// the mapping afterwards.
+func loadRedactor(ctx context.Context, cfg *config.Config) (*redact.Redactor, *redact.Mapping, error) {
+	rules, err := redactRules(cfg)
+	if err != nil || len(rules) == 0 {
+		return nil, nil, err
+	}
+
+	path := cfg.Redact.MappingFile
+	if path == "" {
+		if path, err = utils.GitClient().GitPath(ctx, "ai-docs/redactions.json"); err != nil {
+			return nil, nil, fmt.Errorf("failed to locate redaction mapping: %w", err)
+		}
+	}
+	mapping, err := redact.LoadMapping(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return redact.New(rules, mapping), mapping, nil
+}
+
+// redactRules compiles the rules in cfg.Redact. Literals are matched verbatim.
+func redactRules(cfg *config.Config) ([]redact.Rule, error) {
+	if cfg.Redact == nil {
+		return nil, nil
+	}
+
+	rules := make([]redact.Rule, 0, len(cfg.Redact.Rules))
+	for i, r := range cfg.Redact.Rules {
+		expr := r.Regex
+		if r.Literal != "" {
+			expr = regexp.QuoteMeta(r.Literal)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("redact rule %d: invalid regex: %w", i+1, err)
+		}
+		rules = append(rules, redact.Rule{Regex: re, Placeholder: r.Placeholder})
+	}
+	return rules, nil
+}
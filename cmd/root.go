@@ -1,11 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
 )
 
 var (
@@ -13,6 +20,14 @@ var (
 	dryRun     bool
 	verbose    bool
 	force      bool
+	timeout    time.Duration
+)
+
+// timeoutCtx is the --timeout context; cancelTimeout releases it once the
+// command has finished.
+var (
+	timeoutCtx    context.Context
+	cancelTimeout context.CancelFunc = func() {}
 )
 
 var rootCmd = &cobra.Command{
@@ -20,12 +35,46 @@ var rootCmd = &cobra.Command{
 	Short: "AI documentation management tool",
 	Long: `AI Docs CLI provides a one-command workflow that isolates AI-generated "memory" files 
 onto a dedicated Git branch+worktree, with automatic symlinks and easy sync.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if timeout > 0 {
+			timeoutCtx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(timeoutCtx)
+		}
+	},
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
+	// The first Ctrl-C cancels the running command, which stops git and
+	// cleans up; a second one kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	interrupted := ctx.Err() != nil
+	timedOut := timeoutCtx != nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded)
+	cancelTimeout()
+	stop()
+
+	switch {
+	case err == nil && failures > 0:
+		fmt.Fprintln(os.Stderr, color.YellowString("Finished with %d failed step(s)", failures))
+		os.Exit(failedCode)
+	case err == nil:
+	case interrupted:
+		fmt.Fprintln(os.Stderr, color.RedString("Interrupted: %v", err))
+		os.Exit(exitInterrupted)
+	case timedOut:
+		fmt.Fprintln(os.Stderr, color.RedString("Error: timed out after %s: %v", timeout, err))
+		os.Exit(exitCode(err))
+	default:
+		var exitErr *exitError
+		if !errors.As(err, &exitErr) || !exitErr.silent {
+			fmt.Fprintln(os.Stderr, color.RedString("Error: %v", err))
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -33,6 +82,31 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "config file path (default: .ai-docs.config.yml)")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "show what would be done without making changes")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "abort the command after this long, e.g. 30s or 2m (default: no limit)")
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "fail on per-file warnings and failed steps instead of carrying on")
+
+	// Execute reports errors itself, once and with an exit code.
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w\nRun '%s --help' for usage", err, cmd.CommandPath())
+	})
+}
+
+// loadConfig loads the config and selects the git backend it asks for.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, withCode(exitConfig, err)
+	}
+
+	switch cfg.GitBackend {
+	case config.GitBackendExec:
+		utils.SetGitClient(&utils.ExecGit{})
+	case config.GitBackendGoGit:
+		utils.SetGitClient(&utils.GoGit{})
+	}
+	return cfg, nil
 }
 
 func printInfo(format string, args ...interface{}) {
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/sources"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// sourceCheckout is a configured source fetched for this pull.
+type sourceCheckout struct {
+	config.Source
+	commit string
+	// root is the directory of the checkout holding the agent paths.
+	root string
+}
+
+// fetchSources checks out every configured source into the git directory.
+// Sources pinned in lock are checked out at the pinned commit unless update
+// is set; the others are resolved from their ref. lock is updated with the
+// resulting commits.
+func fetchSources(ctx context.Context, git utils.Git, cfg *config.Config, lock *lockFile, update bool) ([]sourceCheckout, error) {
+	pins := map[string]repoPin{}
+	var checkouts []sourceCheckout
+	for _, s := range cfg.Sources {
+		dir, err := git.GitPath(ctx, filepath.Join("ai-docs", "sources", s.Name))
+		if err != nil {
+			return nil, err
+		}
+
+		pin, pinned := lock.Sources[s.Name]
+		pinned = pinned && !update && pin.URL == s.URL && pin.Ref == s.Ref
+		var commit string
+		switch cached, ok := cachedCheckout(dir); {
+		case pinned && ok && cached.URL == s.URL && cached.Commit == pin.Commit:
+			commit = pin.Commit
+			printInfo("Source %s is at the locked commit %s", s.Name, shortCommit(commit))
+		case pinned:
+			if commit, err = cloneInto(ctx, git, dir, s.URL, pin.Commit); err != nil {
+				return nil, fmt.Errorf("failed to fetch source %s at the locked commit %s - run 'ai-docs pull --update-sources' to move to the latest commit: %w", s.Name, shortCommit(pin.Commit), err)
+			}
+			printInfo("Fetched source %s at the locked commit %s", s.Name, shortCommit(commit))
+		default:
+			commit, err = cloneInto(ctx, git, dir, s.URL, s.Ref)
+			if err != nil {
+				if !ok || cached.URL != s.URL || ctx.Err() != nil {
+					return nil, fmt.Errorf("failed to fetch source %s: %w", s.Name, err)
+				}
+				printWarning("Could not update source %s, using the cached copy at %s: %v", s.Name, shortCommit(cached.Commit), err)
+				commit = cached.Commit
+			} else {
+				printInfo("Fetched source %s at %s", s.Name, shortCommit(commit))
+			}
+		}
+
+		root := dir
+		if s.Subpath != "" {
+			root = filepath.Join(dir, filepath.FromSlash(s.Subpath))
+			if !utils.PathExists(root) {
+				return nil, fmt.Errorf("source %s has no directory %s at %s", s.Name, s.Subpath, shortCommit(commit))
+			}
+		}
+		checkouts = append(checkouts, sourceCheckout{Source: s, commit: commit, root: root})
+		pins[s.Name] = repoPin{URL: s.URL, Ref: s.Ref, Commit: commit}
+	}
+	lock.Sources = pins
+	return checkouts, nil
+}
+
+// mergeSources merges the checked out sources into the local agent paths.
+// The project's own files take precedence over sources, and earlier sources
+// over later ones. It returns the paths that changed.
+func mergeSources(ctx context.Context, cfg *config.Config, checkouts []sourceCheckout) ([]string, error) {
+	var changed []string
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		if err := ctx.Err(); err != nil {
+			return changed, err
+		}
+		p := cfg.AIAgentMemoryContextPath[name]
+		dst := filepath.Join(".", p)
+
+		isDir := false
+		if info, err := os.Stat(dst); err == nil {
+			isDir = info.IsDir()
+		} else {
+			for _, c := range checkouts {
+				if info, err := os.Stat(filepath.Join(c.root, p)); err == nil {
+					isDir = info.IsDir()
+					break
+				}
+			}
+		}
+
+		var (
+			files []string
+			err   error
+		)
+		if isDir {
+			files, err = mergeSourceDir(dst, p, checkouts)
+		} else {
+			files, err = mergeSourceFile(dst, p, checkouts)
+		}
+		if err != nil {
+			return changed, fmt.Errorf("failed to merge sources into %s: %w", p, err)
+		}
+		changed = append(changed, files...)
+	}
+	return changed, nil
+}
+
+// mergeSourceFile replaces the source blocks of the single file dst with the
+// file p of each source.
+func mergeSourceFile(dst, p string, checkouts []sourceCheckout) ([]string, error) {
+	var blocks []sources.Block
+	for _, c := range checkouts {
+		data, err := os.ReadFile(filepath.Join(c.root, p))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, sources.Block{Name: c.Name, Commit: c.commit, Data: data})
+	}
+
+	old, err := os.ReadFile(dst)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	data := sources.Merge(old, blocks)
+	if bytes.Equal(old, data) {
+		return nil, nil
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		// The file held only source blocks.
+		return []string{p}, os.Remove(dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return nil, err
+	}
+	return []string{p}, os.WriteFile(dst, data, 0644)
+}
+
+// mergeSourceDir copies the markdown files of the directory p of each
+// source into dst with a source header, unless the project has a file of
+// its own there. Files copied from sources earlier that no source provides
+// any more are removed.
+func mergeSourceDir(dst, p string, checkouts []sourceCheckout) ([]string, error) {
+	claimed := map[string]bool{}
+	var changed []string
+	for _, c := range checkouts {
+		src := filepath.Join(c.root, p)
+		if !utils.PathExists(src) {
+			continue
+		}
+		err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			if ext := filepath.Ext(rel); ext != ".md" && ext != ".mdc" {
+				printInfo("Skipping %s from source %s: only markdown files are merged", filepath.Join(p, rel), c.Name)
+				return nil
+			}
+			if claimed[rel] {
+				return nil
+			}
+			claimed[rel] = true
+
+			target := filepath.Join(dst, rel)
+			old, err := os.ReadFile(target)
+			if err == nil {
+				if _, ok := sources.Header(old); !ok {
+					printInfo("Keeping the project's %s over source %s", filepath.Join(p, rel), c.Name)
+					return nil
+				}
+			} else if !os.IsNotExist(err) {
+				return err
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			data = sources.AddHeader(data, c.Name, c.commit)
+			if bytes.Equal(old, data) {
+				return nil
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			changed = append(changed, filepath.Join(p, rel))
+			return os.WriteFile(target, data, 0644)
+		})
+		if err != nil {
+			return changed, err
+		}
+	}
+
+	if !utils.PathExists(dst) {
+		return changed, nil
+	}
+	err := filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil || claimed[rel] {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, ok := sources.Header(data); !ok {
+			return nil
+		}
+		changed = append(changed, filepath.Join(p, rel))
+		return os.Remove(path)
+	})
+	return changed, err
+}
+
+// stripSources is the push transform that leaves out what sources merged
+// into the agent paths.
+func stripSources(_ string, data []byte) ([]byte, error) {
+	if !sources.Has(data) {
+		return data, nil
+	}
+	if _, ok := sources.Header(data); ok {
+		return nil, utils.ErrSkipFile
+	}
+	stripped := sources.Strip(data)
+	if len(bytes.TrimSpace(stripped)) == 0 {
+		return nil, utils.ErrSkipFile
+	}
+	return stripped, nil
+}
+
+// repoPin records the commit a repository resolved to.
+type repoPin struct {
+	URL    string `json:"url"`
+	Ref    string `json:"ref,omitempty"`
+	Commit string `json:"commit"`
+}
+
+// cloneInto replaces the checkout in dir with url at ref and records the
+// commit next to it. dir is left alone if the clone fails.
+func cloneInto(ctx context.Context, git utils.Git, dir, url, ref string) (string, error) {
+	tmp := dir + ".tmp"
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(filepath.Dir(tmp), 0755); err != nil {
+		return "", err
+	}
+	commit, err := git.Clone(ctx, localURL(url), ref, tmp)
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	os.RemoveAll(dir)
+	if err := os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(repoPin{URL: url, Ref: ref, Commit: commit})
+	if err != nil {
+		return "", err
+	}
+	return commit, os.WriteFile(dir+".json", data, 0644)
+}
+
+// cachedCheckout returns what cloneInto recorded for dir.
+func cachedCheckout(dir string) (repoPin, bool) {
+	var pin repoPin
+	data, err := os.ReadFile(dir + ".json")
+	if err != nil || json.Unmarshal(data, &pin) != nil || !utils.PathExists(dir) {
+		return repoPin{}, false
+	}
+	return pin, true
+}
+
+// shortCommit abbreviates commit for messages. Commits read from the lock
+// file or the source cache may be shorter than a full hash.
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// localURL makes a relative local repository path absolute, since it is
+// cloned from another directory.
+func localURL(url string) string {
+	if strings.Contains(url, "://") || filepath.IsAbs(url) || !utils.PathExists(url) {
+		return url
+	}
+	if abs, err := filepath.Abs(url); err == nil {
+		return abs
+	}
+	return url
+}
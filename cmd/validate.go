@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/redact"
+	"github.com/trknhr/ai-docs/secrets"
+)
+
+// The checks of the settings owned by commands are registered with the config
+// package, so that every caller of Config.Validate runs them.
+func init() {
+	config.RegisterValidator(validateEncryption)
+	config.RegisterValidator(validateSecretScan)
+	config.RegisterValidator(validateRedact)
+}
+
+func validateEncryption(cfg *config.Config) []config.Issue {
+	e := cfg.Encryption
+	if e == nil {
+		return nil
+	}
+
+	var issues []config.Issue
+	switch {
+	case len(e.Recipients) == 0 && !e.Passphrase:
+		issues = append(issues, config.Issue{
+			Severity: config.SeverityError,
+			Field:    "encryption",
+			Message:  "neither recipients nor passphrase is set",
+			Hint:     "add your public key from 'ai-docs keygen' to encryption.recipients, or set encryption.passphrase: true",
+		})
+	case len(e.Recipients) > 0 && e.Passphrase:
+		issues = append(issues, config.Issue{
+			Severity: config.SeverityError,
+			Field:    "encryption",
+			Message:  "recipients and passphrase cannot be combined",
+			Hint:     "use one of them",
+		})
+	}
+
+	for _, r := range e.Recipients {
+		if _, err := age.ParseX25519Recipient(r); err != nil {
+			issues = append(issues, config.Issue{
+				Severity: config.SeverityError,
+				Field:    "encryption.recipients",
+				Message:  fmt.Sprintf("invalid recipient %q: %v", r, err),
+				Hint:     "recipients are age public keys starting with \"age1\"",
+			})
+		}
+	}
+
+	return issues
+}
+
+func validateSecretScan(cfg *config.Config) []config.Issue {
+	if cfg.SecretScan == nil {
+		return nil
+	}
+
+	var issues []config.Issue
+	seen := map[string]bool{}
+	for i, r := range cfg.SecretScan.Rules {
+		if r.ID == "" {
+			issues = append(issues, config.Issue{
+				Severity: config.SeverityError,
+				Field:    fmt.Sprintf("secretScan.rules[%d]", i),
+				Message:  "rule has no id",
+			})
+		} else if seen[r.ID] {
+			issues = append(issues, config.Issue{
+				Severity: config.SeverityError,
+				Field:    fmt.Sprintf("secretScan.rules[%d]", i),
+				Message:  fmt.Sprintf("duplicate rule id %q", r.ID),
+			})
+		}
+		seen[r.ID] = true
+	}
+
+	if _, err := secretScanner(cfg); err != nil {
+		issues = append(issues, config.Issue{
+			Severity: config.SeverityError,
+			Field:    "secretScan",
+			Message:  err.Error(),
+		})
+	}
+
+	known := map[string]bool{}
+	for _, r := range secrets.DefaultRules() {
+		known[r.ID] = true
+	}
+	for id := range seen {
+		known[id] = true
+	}
+	for _, id := range cfg.SecretScan.Allowlist.Rules {
+		if !known[id] {
+			issues = append(issues, config.Issue{
+				Severity: config.SeverityWarning,
+				Field:    "secretScan.allowlist.rules",
+				Message:  fmt.Sprintf("unknown rule id %q", id),
+			})
+		}
+	}
+
+	return issues
+}
+
+func validateRedact(cfg *config.Config) []config.Issue {
+	if cfg.Redact == nil {
+		return nil
+	}
+
+	var issues []config.Issue
+	for i, r := range cfg.Redact.Rules {
+		field := fmt.Sprintf("redact.rules[%d]", i)
+		if (r.Regex == "") == (r.Literal == "") {
+			issues = append(issues, config.Issue{
+				Severity: config.SeverityError,
+				Field:    field,
+				Message:  "set exactly one of regex and literal",
+			})
+			continue
+		}
+		if r.Placeholder == "" {
+			issues = append(issues, config.Issue{
+				Severity: config.SeverityError,
+				Field:    field,
+				Message:  "placeholder is empty",
+			})
+			continue
+		}
+		if r.Regex != "" {
+			if _, err := regexp.Compile(r.Regex); err != nil {
+				issues = append(issues, config.Issue{
+					Severity: config.SeverityError,
+					Field:    field,
+					Message:  fmt.Sprintf("invalid regex: %v", err),
+				})
+				continue
+			}
+			if cfg.Redact.ExpandOnPull && !strings.Contains(r.Placeholder, redact.IndexVerb) {
+				issues = append(issues, config.Issue{
+					Severity: config.SeverityWarning,
+					Field:    field,
+					Message:  "values matched by this regex share one placeholder and cannot be expanded on pull if they differ",
+					Hint:     fmt.Sprintf("add %q to the placeholder to number them", redact.IndexVerb),
+				})
+			}
+		}
+	}
+
+	return issues
+}
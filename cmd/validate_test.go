@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/trknhr/ai-docs/config"
+)
+
+func TestValidateRunsCommandChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *config.Config)
+		field  string
+	}{
+		{"encryption", func(c *config.Config) { c.Encryption = &config.Encryption{Recipients: []string{"nope"}} }, "encryption.recipients"},
+		{"secret scan", func(c *config.Config) { c.SecretScan = &config.SecretScan{Rules: []config.SecretRule{{Regex: "x"}}} }, "secretScan.rules[0]"},
+		{"redact", func(c *config.Config) { c.Redact = &config.Redact{Rules: []config.RedactRule{{Regex: "("}}} }, "redact.rules[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.Config{MainBranchName: "main", DocBranchNameTemplate: "@doc/{userName}", DocWorktreeDir: ".mem", UserName: "alice"}
+			tt.modify(c)
+			for _, issue := range c.Validate() {
+				if issue.Field == tt.field && issue.Severity == config.SeverityError {
+					return
+				}
+			}
+			t.Errorf("no error for %s in %v", tt.field, c.Validate())
+		})
+	}
+}
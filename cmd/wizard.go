@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/trknhr/ai-docs/agents"
+	"github.com/trknhr/ai-docs/config"
+	"github.com/trknhr/ai-docs/utils"
+)
+
+// runInitWizard builds a config from the agent memory found in the
+// repository, asking the user to confirm each choice unless assumeYes is set,
+// and writes it. Only the paths that were found are configured, each with the
+// ignore pattern the agent registry gives for it. It returns the path of the
+// written config file.
+func runInitWizard(ctx context.Context, path string, assumeYes bool) (string, error) {
+	fmt.Println("No ai-docs configuration found. Let's create one.")
+
+	cfg := &config.Config{
+		MainBranchName:        "main",
+		DocBranchNameTemplate: "@ai-docs/{userName}",
+		DocWorktreeDir:        ".ai-docs",
+	}
+	if current, err := utils.GitClient().CurrentBranch(ctx); err == nil && current != "" {
+		cfg.MainBranchName = current
+	}
+
+	detected := agents.Detect(".")
+	if len(detected) == 0 {
+		return "", withCode(exitConfig, errors.New("no AI agent memory files detected - create one (e.g. CLAUDE.md) or write the config file by hand, then run 'ai-docs init' again"))
+	}
+	for _, d := range detected {
+		printSuccess("Detected %s memory: %s", d.Agent.Name, d.Path)
+	}
+
+	ask := !assumeYes
+	var err error
+
+	if ask {
+		if cfg.MainBranchName, err = promptString(ctx, "Main branch", cfg.MainBranchName); err != nil {
+			return "", err
+		}
+		if cfg.DocBranchNameTemplate, err = promptString(ctx, "Doc branch name template", cfg.DocBranchNameTemplate); err != nil {
+			return "", err
+		}
+		if cfg.DocWorktreeDir, err = promptString(ctx, "Worktree directory", cfg.DocWorktreeDir); err != nil {
+			return "", err
+		}
+	}
+
+	if ask {
+		target, err := promptChoice(ctx, "Write ignore rules to", []string{config.GitignoreTargetGitignore, config.GitignoreTargetExclude}, config.GitignoreTargetGitignore)
+		if err != nil {
+			return "", err
+		}
+		if target == config.GitignoreTargetExclude {
+			cfg.GitignoreTarget = target
+		}
+	}
+
+	cfg.AIAgentMemoryContextPath = map[string]string{}
+	for _, d := range detected {
+		include := true
+		if ask {
+			if include, err = promptYesNo(ctx, fmt.Sprintf("Manage %s memory (%s)?", d.Agent.Name, d.Path), true); err != nil {
+				return "", err
+			}
+		}
+		if include {
+			cfg.AIAgentMemoryContextPath[d.Key()] = d.Path
+			cfg.IgnorePatterns = append(cfg.IgnorePatterns, d.IgnorePattern())
+		}
+	}
+	if len(cfg.AIAgentMemoryContextPath) == 0 {
+		return "", fmt.Errorf("init cancelled: no agent memory selected")
+	}
+
+	fmt.Println("\nAgent memory paths:")
+	for _, name := range sortedKeys(cfg.AIAgentMemoryContextPath) {
+		fmt.Printf("  %s: %s\n", name, cfg.AIAgentMemoryContextPath[name])
+	}
+	fmt.Println("Ignore entries:")
+	for _, entry := range cfg.IgnoreEntries() {
+		fmt.Printf("  %s\n", entry)
+	}
+
+	if path == "" {
+		format := "yml"
+		if ask {
+			if format, err = promptChoice(ctx, "Config format", []string{"yml", "json", "toml"}, format); err != nil {
+				return "", err
+			}
+		}
+		path = ".ai-docs.config." + format
+	}
+
+	if ask {
+		write, err := promptYesNo(ctx, fmt.Sprintf("Write configuration to %s?", path), true)
+		if err != nil {
+			return "", err
+		}
+		if !write {
+			return "", fmt.Errorf("init cancelled")
+		}
+	}
+
+	if err := config.Save(path, cfg); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	printSuccess("Created config file: %s", path)
+
+	return path, nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/trknhr/ai-docs/config"
+)
+
+func TestInitWizardConfiguresDetectedPaths(t *testing.T) {
+	newTestRepo(t)
+	if err := os.Remove(".ai-docs.config.yml"); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := runInitWizard(t.Context(), "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPaths := map[string]string{"Claude (CLAUDE.md)": "CLAUDE.md", "Cursor (.cursor/rules)": ".cursor/rules"}
+	if !reflect.DeepEqual(cfg.AIAgentMemoryContextPath, wantPaths) {
+		t.Errorf("paths = %v, want %v", cfg.AIAgentMemoryContextPath, wantPaths)
+	}
+	if want := []string{"/CLAUDE.md", "/.cursor/rules/"}; !reflect.DeepEqual(cfg.IgnorePatterns, want) {
+		t.Errorf("ignore patterns = %v, want %v", cfg.IgnorePatterns, want)
+	}
+	if len(cfg.Agents) > 0 {
+		t.Errorf("agents = %v, want none", cfg.Agents)
+	}
+}
+
+func TestInitWizardNothingDetected(t *testing.T) {
+	newTestRepo(t)
+	for _, p := range []string{".ai-docs.config.yml", "CLAUDE.md", ".cursor"} {
+		if err := os.RemoveAll(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runInitWizard(t.Context(), "", true); exitCode(err) != exitConfig {
+		t.Fatalf("got %v, want a config error", err)
+	}
+	if _, err := os.Stat(".ai-docs.config.yml"); !os.IsNotExist(err) {
+		t.Errorf("config file written: %v", err)
+	}
+}
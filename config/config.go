@@ -1,15 +1,22 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-yaml/yaml"
 	toml "github.com/pelletier/go-toml/v2"
+	"github.com/trknhr/ai-docs/agents"
 )
 
 type Config struct {
@@ -17,15 +24,167 @@ type Config struct {
 	MainBranchName           string            `yaml:"mainBranchName" json:"mainBranchName" toml:"mainBranchName"`
 	DocBranchNameTemplate    string            `yaml:"docBranchNameTemplate" json:"docBranchNameTemplate" toml:"docBranchNameTemplate"`
 	DocWorktreeDir           string            `yaml:"docWorktreeDir" json:"docWorktreeDir" toml:"docWorktreeDir"`
-	AIAgentMemoryContextPath map[string]string `yaml:"aIAgentMemoryContextPath" json:"aIAgentMemoryContextPath" toml:"aIAgentMemoryContextPath"`
-	IgnorePatterns           []string          `yaml:"ignorePatterns" json:"ignorePatterns" toml:"ignorePatterns"`
-	DocDir                   string            `yaml:"docDir" json:"docDir" toml:"docDir"`
+	Agents                   []string          `yaml:"agents,omitempty" json:"agents,omitempty" toml:"agents,omitempty"`
+	AIAgentMemoryContextPath map[string]string `yaml:"aIAgentMemoryContextPath,omitempty" json:"aIAgentMemoryContextPath,omitempty" toml:"aIAgentMemoryContextPath,omitempty"`
+	IgnorePatterns           []string          `yaml:"ignorePatterns,omitempty" json:"ignorePatterns,omitempty" toml:"ignorePatterns,omitempty"`
+	// GitignoreTarget selects where ignore entries are written: "gitignore"
+	// (the default) or "exclude" for .git/info/exclude.
+	GitignoreTarget string `yaml:"gitignoreTarget,omitempty" json:"gitignoreTarget,omitempty" toml:"gitignoreTarget,omitempty"`
+	// DocDir holds the canonical memory fragments rendered by `ai-docs build`.
+	DocDir string `yaml:"docDir,omitempty" json:"docDir,omitempty" toml:"docDir,omitempty"`
+	// GitBackend selects how git is driven: "exec" runs the git binary,
+	// "go-git" uses the built-in implementation and "auto" (the default)
+	// falls back to go-git when no recent git binary is on PATH.
+	GitBackend string `yaml:"gitBackend,omitempty" json:"gitBackend,omitempty" toml:"gitBackend,omitempty"`
+	// PushRetries is how often a failed push is retried; PushTimeout (a Go
+	// duration such as "2m") bounds the total time spent retrying.
+	PushRetries int    `yaml:"pushRetries,omitempty" json:"pushRetries,omitempty" toml:"pushRetries,omitempty"`
+	PushTimeout string `yaml:"pushTimeout,omitempty" json:"pushTimeout,omitempty" toml:"pushTimeout,omitempty"`
+	// Encryption, when set, encrypts every file committed to the doc branch.
+	Encryption *Encryption `yaml:"encryption,omitempty" json:"encryption,omitempty" toml:"encryption,omitempty"`
+	// SecretScan adds rules and allowlist entries to the secret scan that
+	// push runs before committing.
+	SecretScan *SecretScan `yaml:"secretScan,omitempty" json:"secretScan,omitempty" toml:"secretScan,omitempty"`
+	// Redact replaces sensitive values with placeholders on push.
+	Redact *Redact `yaml:"redact,omitempty" json:"redact,omitempty" toml:"redact,omitempty"`
+	// Sync keeps the memory files of several agents consistent.
+	Sync *Sync `yaml:"sync,omitempty" json:"sync,omitempty" toml:"sync,omitempty"`
+	// Include configures where include directives can read from.
+	Include *Include `yaml:"include,omitempty" json:"include,omitempty" toml:"include,omitempty"`
+	// Sources are repositories whose memory is merged into the agent paths
+	// on pull. Earlier sources take precedence over later ones, and the
+	// project's own files over all of them.
+	Sources []Source `yaml:"sources,omitempty" json:"sources,omitempty" toml:"sources,omitempty"`
+	// LockFile selects where pull records what it copied: "committed" (the
+	// default) writes .ai-docs.lock next to the config file, "local" keeps
+	// it in the git directory.
+	LockFile string `yaml:"lockFile,omitempty" json:"lockFile,omitempty" toml:"lockFile,omitempty"`
 }
 
-func LoadConfig(configPath string) (*Config, error) {
-	if configPath == "" {
-		configPath = ".ai-docs.config.yml"
+// Encryption configures at-rest encryption of doc branch files with age.
+// Files are encrypted either to Recipients or with a passphrase, never both.
+type Encryption struct {
+	// Recipients are age X25519 public keys ("age1...").
+	Recipients []string `yaml:"recipients,omitempty" json:"recipients,omitempty" toml:"recipients,omitempty"`
+	// IdentityFile holds the age private keys used to decrypt. It defaults
+	// to ai-docs/identity.txt in the user config directory.
+	IdentityFile string `yaml:"identityFile,omitempty" json:"identityFile,omitempty" toml:"identityFile,omitempty"`
+	// Passphrase encrypts with a passphrase taken from AI_DOCS_PASSPHRASE or
+	// asked for on the terminal.
+	Passphrase bool `yaml:"passphrase,omitempty" json:"passphrase,omitempty" toml:"passphrase,omitempty"`
+}
+
+// SecretScan configures the secret scanner run by push.
+type SecretScan struct {
+	// Disabled turns the scan off.
+	Disabled bool `yaml:"disabled,omitempty" json:"disabled,omitempty" toml:"disabled,omitempty"`
+	// Rules are checked in addition to the built-in rules.
+	Rules     []SecretRule    `yaml:"rules,omitempty" json:"rules,omitempty" toml:"rules,omitempty"`
+	Allowlist SecretAllowlist `yaml:"allowlist,omitempty" json:"allowlist,omitempty" toml:"allowlist,omitempty"`
+}
+
+// SecretRule is a custom secret scanner rule.
+type SecretRule struct {
+	ID          string `yaml:"id" json:"id" toml:"id"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty" toml:"description,omitempty"`
+	Regex       string `yaml:"regex" json:"regex" toml:"regex"`
+	// SecretGroup is the capture group holding the secret (0: whole match).
+	SecretGroup int `yaml:"secretGroup,omitempty" json:"secretGroup,omitempty" toml:"secretGroup,omitempty"`
+	// Entropy is the minimum Shannon entropy of the secret in bits per character.
+	Entropy float64 `yaml:"entropy,omitempty" json:"entropy,omitempty" toml:"entropy,omitempty"`
+}
+
+// SecretAllowlist suppresses secret scanner findings.
+type SecretAllowlist struct {
+	// Regexes are matched against the secret itself.
+	Regexes []string `yaml:"regexes,omitempty" json:"regexes,omitempty" toml:"regexes,omitempty"`
+	// Paths are glob patterns for files that are not scanned.
+	Paths []string `yaml:"paths,omitempty" json:"paths,omitempty" toml:"paths,omitempty"`
+	// Rules are IDs of rules that are turned off.
+	Rules []string `yaml:"rules,omitempty" json:"rules,omitempty" toml:"rules,omitempty"`
+}
+
+// Redact configures the redaction applied to files copied to the doc branch.
+type Redact struct {
+	Rules []RedactRule `yaml:"rules,omitempty" json:"rules,omitempty" toml:"rules,omitempty"`
+	// ExpandOnPull replaces placeholders with the original values on pull.
+	ExpandOnPull bool `yaml:"expandOnPull,omitempty" json:"expandOnPull,omitempty" toml:"expandOnPull,omitempty"`
+	// MappingFile records the value behind each placeholder. It defaults to
+	// ai-docs/redactions.json in the git directory and must not be committed.
+	MappingFile string `yaml:"mappingFile,omitempty" json:"mappingFile,omitempty" toml:"mappingFile,omitempty"`
+}
+
+// RedactRule replaces a regex or literal value with Placeholder. A "{n}" in
+// the placeholder is numbered per distinct value.
+type RedactRule struct {
+	Regex       string `yaml:"regex,omitempty" json:"regex,omitempty" toml:"regex,omitempty"`
+	Literal     string `yaml:"literal,omitempty" json:"literal,omitempty" toml:"literal,omitempty"`
+	Placeholder string `yaml:"placeholder" json:"placeholder" toml:"placeholder"`
+}
+
+// Sync configures how agent memory is kept consistent across agents.
+type Sync struct {
+	// Derive regenerates agent files from one canonical agent on push.
+	Derive *Derive `yaml:"derive,omitempty" json:"derive,omitempty" toml:"derive,omitempty"`
+}
+
+// Derive names the canonical agent and the agents whose files are generated
+// from it, by agent ID.
+type Derive struct {
+	From string   `yaml:"from" json:"from" toml:"from"`
+	To   []string `yaml:"to" json:"to" toml:"to"`
+}
+
+// Include configures the sources of include directives besides the doc
+// branch and URLs.
+type Include struct {
+	// Repos are git repositories that directives refer to as "name:path".
+	Repos map[string]IncludeRepo `yaml:"repos,omitempty" json:"repos,omitempty" toml:"repos,omitempty"`
+}
+
+// IncludeRepo is a git repository read by include directives.
+type IncludeRepo struct {
+	URL string `yaml:"url" json:"url" toml:"url"`
+	// Ref is a branch, tag or commit; it defaults to the default branch.
+	Ref string `yaml:"ref,omitempty" json:"ref,omitempty" toml:"ref,omitempty"`
+}
+
+// Source is a git repository holding shared memory, laid out like the agent
+// paths of a project.
+type Source struct {
+	Name string `yaml:"name" json:"name" toml:"name"`
+	URL  string `yaml:"url" json:"url" toml:"url"`
+	// Ref is a branch, tag or commit; it defaults to the default branch.
+	Ref string `yaml:"ref,omitempty" json:"ref,omitempty" toml:"ref,omitempty"`
+	// Subpath is the directory of the repository that holds the agent paths.
+	Subpath string `yaml:"subpath,omitempty" json:"subpath,omitempty" toml:"subpath,omitempty"`
+}
+
+// DefaultConfigPaths lists the config file names looked up, in order, when no
+// explicit path is given.
+var DefaultConfigPaths = []string{
+	".ai-docs.config.yml",
+	".ai-docs.config.yaml",
+	".ai-docs.config.json",
+	".ai-docs.config.toml",
+}
+
+// ResolvePath returns configPath if set, otherwise the first default config
+// file that exists, falling back to .ai-docs.config.yml.
+func ResolvePath(configPath string) string {
+	if configPath != "" {
+		return configPath
+	}
+	for _, p := range DefaultConfigPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
 	}
+	return DefaultConfigPaths[0]
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	configPath = ResolvePath(configPath)
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -36,42 +195,235 @@ func LoadConfig(configPath string) (*Config, error) {
 		MainBranchName:        "main",
 		DocBranchNameTemplate: "@doc/{userName}",
 		DocWorktreeDir:        ".mem",
-		AIAgentMemoryContextPath: map[string]string{
+		DocDir:                "docs/ai",
+		PushRetries:           3,
+	}
+
+	if err := decodeStrict(configPath, data, cfg); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Agents) > 0 {
+		if err := cfg.applyAgents(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Defaults for collections are applied after decoding, and only if the
+	// key is absent, so that a config listing its own agents replaces them
+	// instead of being merged into them, and an empty map or list sets none.
+	if cfg.AIAgentMemoryContextPath == nil {
+		cfg.AIAgentMemoryContextPath = map[string]string{
 			"Cline":  "memory-bank",
 			"Claude": ".ai-memory",
 			"Gemini": ".gemini/context",
 			"Cursor": ".cursor/rules",
-		},
-		IgnorePatterns: []string{
+		}
+	}
+	if cfg.IgnorePatterns == nil {
+		cfg.IgnorePatterns = []string{
 			"/memory-bank/",
 			"/.ai-memory/",
 			"/.gemini/context/",
 			"/.cursor/rules/",
-		},
-		DocDir: "docs/ai",
+		}
+	}
+
+	if cfg.UserName == "" {
+		cfg.UserName = getGitUserName()
 	}
 
+	return cfg, nil
+}
+
+// Save writes cfg to path in the format implied by the file extension.
+func Save(path string, cfg *Config) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml":
+		data, err = yaml.Marshal(cfg)
+	case ".json":
+		data, err = json.MarshalIndent(cfg, "", "  ")
+		data = append(data, '\n')
+	case ".toml":
+		data, err = toml.Marshal(cfg)
+	default:
+		return fmt.Errorf("unsupported config file format: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// applyAgents derives agent paths and ignore patterns from the agent registry
+// for every entry in Agents. Explicitly configured paths take precedence.
+func (c *Config) applyAgents() error {
+	if c.AIAgentMemoryContextPath == nil {
+		c.AIAgentMemoryContextPath = map[string]string{}
+	}
+	if c.IgnorePatterns == nil {
+		c.IgnorePatterns = []string{}
+	}
+
+	configured := map[string]struct{}{}
+	for _, p := range c.AIAgentMemoryContextPath {
+		configured[normalizePath(p)] = struct{}{}
+	}
+
+	for _, id := range c.Agents {
+		agent, ok := agents.Lookup(id)
+		if !ok {
+			msg := fmt.Sprintf("unknown agent %q in agents", id)
+			if s := closestMatch(id, agents.IDs()); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			return fmt.Errorf("%s; known agents: %s", msg, strings.Join(agents.IDs(), ", "))
+		}
+
+		for key, p := range agent.ContextPaths() {
+			if _, ok := configured[normalizePath(p)]; ok {
+				continue
+			}
+			if _, ok := c.AIAgentMemoryContextPath[key]; ok {
+				continue
+			}
+			c.AIAgentMemoryContextPath[key] = p
+			configured[normalizePath(p)] = struct{}{}
+		}
+
+		for i, p := range agent.Paths {
+			if c.isIgnored(p) {
+				continue
+			}
+			c.IgnorePatterns = append(c.IgnorePatterns, agent.IgnorePatterns[i])
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) isIgnored(p string) bool {
+	for _, pattern := range c.IgnorePatterns {
+		if IgnorePatternCovers(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeStrict unmarshals data into cfg according to the file extension and
+// rejects keys that do not map to a Config field.
+func decodeStrict(configPath string, data []byte, cfg *Config) error {
 	ext := filepath.Ext(configPath)
+
+	raw := map[string]interface{}{}
+	var err error
 	switch ext {
 	case ".yml", ".yaml":
-		err = yaml.Unmarshal(data, cfg)
+		err = yaml.Unmarshal(data, &raw)
 	case ".json":
-		err = json.Unmarshal(data, cfg)
+		err = json.Unmarshal(data, &raw)
 	case ".toml":
-		err = toml.Unmarshal(data, cfg)
+		err = toml.Unmarshal(data, &raw)
 	default:
-		return nil, fmt.Errorf("unsupported config file format: %s", ext)
+		return fmt.Errorf("unsupported config file format: %s", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := checkUnknownKeys(raw); err != nil {
+		return err
 	}
 
+	switch ext {
+	case ".yml", ".yaml":
+		err = yaml.UnmarshalStrict(data, cfg)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(cfg)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	if cfg.UserName == "" {
-		cfg.UserName = getGitUserName()
+	// A key that is present counts as set even if it is empty, which not
+	// every decoder reflects in cfg.
+	if _, ok := raw["aIAgentMemoryContextPath"]; ok && cfg.AIAgentMemoryContextPath == nil {
+		cfg.AIAgentMemoryContextPath = map[string]string{}
+	}
+	if _, ok := raw["ignorePatterns"]; ok && cfg.IgnorePatterns == nil {
+		cfg.IgnorePatterns = []string{}
 	}
 
-	return cfg, nil
+	return nil
+}
+
+// UnknownKeyError reports top-level keys in a config file that do not
+// correspond to any Config field.
+type UnknownKeyError struct {
+	Keys        []string
+	Suggestions map[string]string
+}
+
+func (e *UnknownKeyError) Error() string {
+	parts := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		if s, ok := e.Suggestions[key]; ok {
+			parts = append(parts, fmt.Sprintf("%q (did you mean %q?)", key, s))
+		} else {
+			parts = append(parts, fmt.Sprintf("%q", key))
+		}
+	}
+	return fmt.Sprintf("unknown config key(s): %s", strings.Join(parts, ", "))
+}
+
+func checkUnknownKeys(raw map[string]interface{}) error {
+	known := KnownKeys()
+	knownSet := make(map[string]struct{}, len(known))
+	for _, k := range known {
+		knownSet[k] = struct{}{}
+	}
+
+	var unknown []string
+	suggestions := map[string]string{}
+	for key := range raw {
+		if _, ok := knownSet[key]; ok {
+			continue
+		}
+		unknown = append(unknown, key)
+		if s := closestMatch(key, known); s != "" {
+			suggestions[key] = s
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return &UnknownKeyError{Keys: unknown, Suggestions: suggestions}
+}
+
+// KnownKeys returns the top-level config keys accepted by LoadConfig.
+func KnownKeys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag != "" && tag != "-" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
 }
 
 func getGitUserName() string {
@@ -81,6 +433,13 @@ func getGitUserName() string {
 		return strings.TrimSpace(string(output))
 	}
 
+	// Without a git binary, read the same settings with go-git.
+	if repo, err := gogit.PlainOpenWithOptions(".", &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true}); err == nil {
+		if cfg, err := repo.ConfigScoped(gitconfig.SystemScope); err == nil && cfg.User.Name != "" {
+			return cfg.User.Name
+		}
+	}
+
 	whoamiCmd := exec.Command("whoami")
 	whoamiOutput, err := whoamiCmd.Output()
 	if err != nil || strings.TrimSpace(string(whoamiOutput)) == "" {
@@ -89,6 +448,96 @@ func getGitUserName() string {
 	return strings.TrimSpace(string(whoamiOutput))
 }
 
+const (
+	GitignoreTargetGitignore = "gitignore"
+	GitignoreTargetExclude   = "exclude"
+)
+
+const (
+	LockFileCommitted = "committed"
+	LockFileLocal     = "local"
+)
+
+// PushTimeoutDuration returns PushTimeout as a duration; zero means no limit.
+func (c *Config) PushTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.PushTimeout)
+	return d
+}
+
+// ConfiguredPath returns the index in a.Paths of the first of the agent's
+// paths that is listed in aIAgentMemoryContextPath.
+func (c *Config) ConfiguredPath(a agents.Agent) (int, bool) {
+	for i, p := range a.Paths {
+		for _, configured := range c.AIAgentMemoryContextPath {
+			if normalizePath(configured) == normalizePath(p) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// EncryptionEnabled reports whether doc branch files are encrypted.
+func (c *Config) EncryptionEnabled() bool {
+	return c.Encryption != nil && (len(c.Encryption.Recipients) > 0 || c.Encryption.Passphrase)
+}
+
+// IdentityFilePath returns the age identity file, expanding a leading "~/".
+func (e *Encryption) IdentityFilePath() string {
+	path := e.IdentityFile
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(dir, "ai-docs", "identity.txt")
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+const (
+	GitBackendAuto  = "auto"
+	GitBackendExec  = "exec"
+	GitBackendGoGit = "go-git"
+)
+
+// IgnoreEntries returns the lines ai-docs maintains in its managed ignore
+// block: the configured ignorePatterns plus anchored entries for any agent
+// path or worktree directory they do not already cover.
+func (c *Config) IgnoreEntries() []string {
+	entries := append([]string{}, c.IgnorePatterns...)
+
+	extra := make([]string, 0, len(c.AIAgentMemoryContextPath)+1)
+	for _, name := range c.sortedAgentNames() {
+		extra = append(extra, c.AIAgentMemoryContextPath[name])
+	}
+	extra = append(extra, c.DocWorktreeDir)
+
+	for _, p := range extra {
+		p = normalizePath(p)
+		if p == "" || p == "." {
+			continue
+		}
+		covered := false
+		for _, pattern := range entries {
+			if IgnorePatternCovers(pattern, p) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			entries = append(entries, "/"+p)
+		}
+	}
+
+	return entries
+}
+
 func (c *Config) GetDocBranchName() string {
 	return strings.ReplaceAll(c.DocBranchNameTemplate, "{userName}", c.UserName)
 }
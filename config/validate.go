@@ -0,0 +1,591 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trknhr/ai-docs/agents"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a single problem found while validating a Config.
+type Issue struct {
+	Severity Severity
+	Field    string
+	Message  string
+	Hint     string
+}
+
+func (i Issue) String() string {
+	s := fmt.Sprintf("%s: %s", i.Field, i.Message)
+	if i.Hint != "" {
+		s += fmt.Sprintf(" (hint: %s)", i.Hint)
+	}
+	return s
+}
+
+// HasErrors reports whether any of the issues is an error rather than a warning.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validator checks the settings of a Config that belong to another package,
+// such as encryption or the secret scan.
+type Validator func(c *Config) []Issue
+
+var validators []Validator
+
+// RegisterValidator adds v to the checks run by Validate.
+func RegisterValidator(v Validator) {
+	validators = append(validators, v)
+}
+
+// Validate checks the config for mistakes that the decoder cannot catch:
+// invalid branch names, conflicting paths and ignore patterns that do not
+// line up with the agent paths.
+func (c *Config) Validate() []Issue {
+	var issues []Issue
+
+	issues = append(issues, c.validateBranches()...)
+	issues = append(issues, c.validatePaths()...)
+	issues = append(issues, c.validateIgnorePatterns()...)
+	for _, v := range validators {
+		issues = append(issues, v(c)...)
+	}
+	issues = append(issues, c.validateSync()...)
+	issues = append(issues, c.validateInclude()...)
+	issues = append(issues, c.validateSources()...)
+
+	switch c.GitignoreTarget {
+	case "", GitignoreTargetGitignore, GitignoreTargetExclude:
+	default:
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "gitignoreTarget",
+			Message:  fmt.Sprintf("unknown target %q", c.GitignoreTarget),
+			Hint:     fmt.Sprintf("use %q or %q", GitignoreTargetGitignore, GitignoreTargetExclude),
+		})
+	}
+
+	switch c.LockFile {
+	case "", LockFileCommitted, LockFileLocal:
+	default:
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "lockFile",
+			Message:  fmt.Sprintf("unknown lock file location %q", c.LockFile),
+			Hint:     fmt.Sprintf("use %q or %q", LockFileCommitted, LockFileLocal),
+		})
+	}
+
+	switch c.GitBackend {
+	case "", GitBackendAuto, GitBackendExec, GitBackendGoGit:
+	default:
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "gitBackend",
+			Message:  fmt.Sprintf("unknown backend %q", c.GitBackend),
+			Hint:     fmt.Sprintf("use %q, %q or %q", GitBackendAuto, GitBackendExec, GitBackendGoGit),
+		})
+	}
+
+	if c.PushRetries < 0 {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "pushRetries",
+			Message:  fmt.Sprintf("must not be negative, got %d", c.PushRetries),
+		})
+	}
+	if c.PushTimeout != "" {
+		if d, err := time.ParseDuration(c.PushTimeout); err != nil || d < 0 {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    "pushTimeout",
+				Message:  fmt.Sprintf("invalid duration %q", c.PushTimeout),
+				Hint:     `use a duration such as "90s" or "2m"`,
+			})
+		}
+	}
+
+	return issues
+}
+
+func (c *Config) validateBranches() []Issue {
+	var issues []Issue
+
+	if c.MainBranchName == "" {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "mainBranchName",
+			Message:  "must not be empty",
+		})
+	} else if err := CheckRefName(c.MainBranchName); err != nil {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "mainBranchName",
+			Message:  fmt.Sprintf("%q is not a valid branch name: %v", c.MainBranchName, err),
+		})
+	}
+
+	if c.DocBranchNameTemplate == "" {
+		return append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "docBranchNameTemplate",
+			Message:  "must not be empty",
+			Hint:     `use something like "@ai-docs/{userName}"`,
+		})
+	}
+
+	sample := strings.ReplaceAll(c.DocBranchNameTemplate, "{userName}", "user")
+	if err := CheckRefName(sample); err != nil {
+		return append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "docBranchNameTemplate",
+			Message:  fmt.Sprintf("%q does not yield a valid branch name: %v", c.DocBranchNameTemplate, err),
+			Hint:     `use something like "@ai-docs/{userName}"`,
+		})
+	}
+
+	if err := CheckRefName(c.GetDocBranchName()); err != nil {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "userName",
+			Message:  fmt.Sprintf("%q makes the doc branch name %q invalid: %v", c.UserName, c.GetDocBranchName(), err),
+			Hint:     "set userName to a value without spaces or special characters",
+		})
+	}
+
+	if !strings.Contains(c.DocBranchNameTemplate, "{userName}") {
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Field:    "docBranchNameTemplate",
+			Message:  "does not contain {userName}; all users will share one doc branch",
+		})
+	}
+
+	return issues
+}
+
+func (c *Config) validatePaths() []Issue {
+	var issues []Issue
+
+	worktree := normalizePath(c.DocWorktreeDir)
+	if worktree == "" || worktree == "." {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "docWorktreeDir",
+			Message:  "must be a subdirectory of the repository",
+		})
+	}
+
+	names := c.sortedAgentNames()
+	for _, name := range names {
+		p := normalizePath(c.AIAgentMemoryContextPath[name])
+		field := fmt.Sprintf("aIAgentMemoryContextPath.%s", name)
+
+		if p == "" || p == "." {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field,
+				Message:  "must be a path inside the repository",
+			})
+			continue
+		}
+		if p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field,
+				Message:  fmt.Sprintf("%q points outside the repository", c.AIAgentMemoryContextPath[name]),
+			})
+			continue
+		}
+
+		if worktree != "" && worktree != "." {
+			if pathWithin(worktree, p) {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Field:    "docWorktreeDir",
+					Message:  fmt.Sprintf("%q is inside the %s agent path %q", c.DocWorktreeDir, name, c.AIAgentMemoryContextPath[name]),
+					Hint:     "move docWorktreeDir to its own top-level directory such as .ai-docs",
+				})
+			} else if pathWithin(p, worktree) {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Field:    field,
+					Message:  fmt.Sprintf("%q is inside docWorktreeDir %q", c.AIAgentMemoryContextPath[name], c.DocWorktreeDir),
+				})
+			}
+		}
+	}
+
+	for i, a := range names {
+		pa := normalizePath(c.AIAgentMemoryContextPath[a])
+		for _, b := range names[i+1:] {
+			pb := normalizePath(c.AIAgentMemoryContextPath[b])
+			if pa == "" || pb == "" {
+				continue
+			}
+			if pathWithin(pa, pb) || pathWithin(pb, pa) {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Field:    "aIAgentMemoryContextPath",
+					Message:  fmt.Sprintf("paths of %s (%q) and %s (%q) overlap", a, pa, b, pb),
+					Hint:     "each agent path must be copied independently; remove one of them",
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+func (c *Config) validateIgnorePatterns() []Issue {
+	var issues []Issue
+
+	names := c.sortedAgentNames()
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		paths = append(paths, normalizePath(c.AIAgentMemoryContextPath[name]))
+	}
+
+	for _, name := range names {
+		p := normalizePath(c.AIAgentMemoryContextPath[name])
+		if p == "" {
+			continue
+		}
+		if !c.isIgnored(p) {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Field:    fmt.Sprintf("aIAgentMemoryContextPath.%s", name),
+				Message:  fmt.Sprintf("%q is not covered by any ignorePatterns entry", p),
+				Hint:     fmt.Sprintf("ai-docs adds %q to its ignore block; list it in ignorePatterns to make this explicit", "/"+p),
+			})
+		}
+	}
+
+	for i, pattern := range c.IgnorePatterns {
+		trimmed := strings.TrimSpace(pattern)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "!") {
+			continue
+		}
+		covers := false
+		for _, p := range paths {
+			if IgnorePatternCovers(pattern, p) {
+				covers = true
+				break
+			}
+		}
+		if covers || IgnorePatternCovers(pattern, normalizePath(c.DocWorktreeDir)) {
+			continue
+		}
+
+		issue := Issue{
+			Severity: SeverityWarning,
+			Field:    fmt.Sprintf("ignorePatterns[%d]", i),
+			Message:  fmt.Sprintf("%q does not cover any agent path", pattern),
+		}
+		if s := closestMatch(strings.Trim(trimmed, "/"), paths); s != "" {
+			issue.Hint = fmt.Sprintf("did you mean %q?", s)
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+func (c *Config) validateSync() []Issue {
+	if c.Sync == nil || c.Sync.Derive == nil {
+		return nil
+	}
+
+	var issues []Issue
+	d := c.Sync.Derive
+	lookup := func(field, id string) (agents.Agent, bool) {
+		agent, ok := agents.Lookup(id)
+		if !ok {
+			issue := Issue{
+				Severity: SeverityError,
+				Field:    field,
+				Message:  fmt.Sprintf("unknown agent %q", id),
+			}
+			if s := closestMatch(id, agents.IDs()); s != "" {
+				issue.Hint = fmt.Sprintf("did you mean %q?", s)
+			}
+			issues = append(issues, issue)
+		}
+		return agent, ok
+	}
+
+	from, fromOK := lookup("sync.derive.from", d.From)
+	if len(d.To) == 0 {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Field:    "sync.derive.to",
+			Message:  "no agents to derive",
+		})
+	}
+	for i, id := range d.To {
+		field := fmt.Sprintf("sync.derive.to[%d]", i)
+		agent, ok := lookup(field, id)
+		if !ok {
+			continue
+		}
+		if fromOK && agent.ID == from.ID {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field,
+				Message:  fmt.Sprintf("%s is also sync.derive.from", agent.ID),
+			})
+			continue
+		}
+		if _, ok := c.ConfiguredPath(agent); !ok {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Field:    field,
+				Message:  fmt.Sprintf("none of the %s paths is in aIAgentMemoryContextPath, so the derived files are not pushed", agent.Name),
+				Hint:     fmt.Sprintf("add %q to agents", agent.ID),
+			})
+		}
+	}
+
+	return issues
+}
+
+func (c *Config) validateInclude() []Issue {
+	if c.Include == nil {
+		return nil
+	}
+
+	var issues []Issue
+	names := make([]string, 0, len(c.Include.Repos))
+	for name := range c.Include.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := fmt.Sprintf("include.repos.%s", name)
+		if name == "" || strings.ContainsAny(name, ":/\\") || strings.HasPrefix(name, ".") {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field,
+				Message:  fmt.Sprintf("invalid name %q", name),
+				Hint:     "use a simple name such as \"shared\"",
+			})
+		}
+		if c.Include.Repos[name].URL == "" {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field + ".url",
+				Message:  "url is empty",
+			})
+		}
+	}
+
+	return issues
+}
+
+func (c *Config) validateSources() []Issue {
+	var issues []Issue
+	seen := map[string]bool{}
+	for i, s := range c.Sources {
+		field := fmt.Sprintf("sources[%d]", i)
+		switch {
+		case s.Name == "" || strings.ContainsAny(s.Name, ":/\\@ ") || strings.HasPrefix(s.Name, "."):
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field + ".name",
+				Message:  fmt.Sprintf("invalid name %q", s.Name),
+				Hint:     "use a simple name such as \"org\"",
+			})
+		case seen[s.Name]:
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field + ".name",
+				Message:  fmt.Sprintf("duplicate source %q", s.Name),
+			})
+		}
+		seen[s.Name] = true
+		if s.URL == "" {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Field:    field + ".url",
+				Message:  "url is empty",
+			})
+		}
+		if s.Subpath != "" {
+			clean := path.Clean("/" + filepath.ToSlash(s.Subpath))[1:]
+			if clean == "" || clean != strings.TrimPrefix(path.Clean(filepath.ToSlash(s.Subpath)), "./") {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Field:    field + ".subpath",
+					Message:  fmt.Sprintf("invalid subpath %q", s.Subpath),
+					Hint:     "use a directory inside the repository, such as \"memory\"",
+				})
+			}
+		}
+	}
+	return issues
+}
+
+func (c *Config) sortedAgentNames() []string {
+	names := make([]string, 0, len(c.AIAgentMemoryContextPath))
+	for name := range c.AIAgentMemoryContextPath {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckRefName validates a branch name using the rules of git check-ref-format.
+func CheckRefName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("empty name")
+	case name == "@":
+		return fmt.Errorf("cannot be '@'")
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("cannot start with '-'")
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/"):
+		return fmt.Errorf("cannot start or end with '/'")
+	case strings.HasSuffix(name, "."):
+		return fmt.Errorf("cannot end with '.'")
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("cannot contain '..'")
+	case strings.Contains(name, "//"):
+		return fmt.Errorf("cannot contain '//'")
+	case strings.Contains(name, "@{"):
+		return fmt.Errorf("cannot contain '@{'")
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("cannot contain control characters")
+		}
+		if strings.ContainsRune(" ~^:?*[\\", r) {
+			return fmt.Errorf("cannot contain %q", r)
+		}
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			return fmt.Errorf("component %q cannot start with '.'", component)
+		}
+		if strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("component %q cannot end with '.lock'", component)
+		}
+	}
+
+	return nil
+}
+
+// IgnorePatternCovers reports whether a .gitignore-style pattern would ignore
+// the given repository-relative path, either directly or via a parent directory.
+func IgnorePatternCovers(pattern, p string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "#") || strings.HasPrefix(pattern, "!") {
+		return false
+	}
+	p = normalizePath(p)
+	if p == "" {
+		return false
+	}
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "**/")
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	for candidate := p; candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		if dirOnly && candidate == p && isRegularFile(p) {
+			continue
+		}
+
+		subject := candidate
+		if !anchored {
+			subject = path.Base(candidate)
+		}
+		if ok, _ := path.Match(pattern, subject); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRegularFile(p string) bool {
+	info, err := os.Stat(filepath.FromSlash(p))
+	return err == nil && info.Mode().IsRegular()
+}
+
+func normalizePath(p string) string {
+	if strings.TrimSpace(p) == "" {
+		return ""
+	}
+	return path.Clean(filepath.ToSlash(p))
+}
+
+func pathWithin(child, parent string) bool {
+	return child == parent || strings.HasPrefix(child, parent+"/")
+}
+
+// closestMatch returns the candidate closest to s by edit distance, or "" if
+// none is close enough to be a plausible typo.
+func closestMatch(s string, candidates []string) string {
+	best := ""
+	bestDist := -1
+	for _, c := range candidates {
+		d := levenshtein(strings.ToLower(s), strings.ToLower(c))
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+
+	limit := len(s) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDist < 0 || bestDist > limit {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
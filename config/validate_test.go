@@ -0,0 +1,295 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckRefName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"main", true},
+		{"@doc/alice", true},
+		{"feature/x-1.2", true},
+		{"", false},
+		{"@", false},
+		{"-branch", false},
+		{"/branch", false},
+		{"branch/", false},
+		{"branch.", false},
+		{"a..b", false},
+		{"a//b", false},
+		{"a@{b", false},
+		{"has space", false},
+		{"tilde~", false},
+		{"caret^", false},
+		{"colon:", false},
+		{"question?", false},
+		{"star*", false},
+		{"bracket[", false},
+		{"back\\slash", false},
+		{"tab\t", false},
+		{"a/.hidden", false},
+		{"a/b.lock", false},
+	}
+	for _, tt := range tests {
+		err := CheckRefName(tt.name)
+		if (err == nil) != tt.valid {
+			t.Errorf("CheckRefName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func validConfig() *Config {
+	return &Config{
+		UserName:              "alice",
+		MainBranchName:        "main",
+		DocBranchNameTemplate: "@doc/{userName}",
+		DocWorktreeDir:        ".mem",
+		AIAgentMemoryContextPath: map[string]string{
+			"Claude": "CLAUDE.md",
+			"Cursor": ".cursor/rules",
+		},
+		IgnorePatterns: []string{"/CLAUDE.md", "/.cursor/rules/"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		field    string
+		severity Severity
+	}{
+		{
+			name:   "valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name:     "empty main branch",
+			modify:   func(c *Config) { c.MainBranchName = "" },
+			field:    "mainBranchName",
+			severity: SeverityError,
+		},
+		{
+			name:     "invalid main branch",
+			modify:   func(c *Config) { c.MainBranchName = "ma in" },
+			field:    "mainBranchName",
+			severity: SeverityError,
+		},
+		{
+			name:     "invalid doc branch template",
+			modify:   func(c *Config) { c.DocBranchNameTemplate = "doc..{userName}" },
+			field:    "docBranchNameTemplate",
+			severity: SeverityError,
+		},
+		{
+			name:     "shared doc branch",
+			modify:   func(c *Config) { c.DocBranchNameTemplate = "@doc/all" },
+			field:    "docBranchNameTemplate",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "user name with a space",
+			modify:   func(c *Config) { c.UserName = "Alice Smith" },
+			field:    "userName",
+			severity: SeverityError,
+		},
+		{
+			name:     "worktree is the repository",
+			modify:   func(c *Config) { c.DocWorktreeDir = "./" },
+			field:    "docWorktreeDir",
+			severity: SeverityError,
+		},
+		{
+			name:     "worktree inside an agent path",
+			modify:   func(c *Config) { c.DocWorktreeDir = ".cursor/rules/mem" },
+			field:    "docWorktreeDir",
+			severity: SeverityError,
+		},
+		{
+			name:     "agent path outside the repository",
+			modify:   func(c *Config) { c.AIAgentMemoryContextPath["Claude"] = "../CLAUDE.md" },
+			field:    "aIAgentMemoryContextPath.Claude",
+			severity: SeverityError,
+		},
+		{
+			name:     "overlapping agent paths",
+			modify:   func(c *Config) { c.AIAgentMemoryContextPath["Other"] = ".cursor" },
+			field:    "aIAgentMemoryContextPath",
+			severity: SeverityError,
+		},
+		{
+			name:     "unknown gitignore target",
+			modify:   func(c *Config) { c.GitignoreTarget = "nowhere" },
+			field:    "gitignoreTarget",
+			severity: SeverityError,
+		},
+		{
+			name:     "unknown lock file location",
+			modify:   func(c *Config) { c.LockFile = "remote" },
+			field:    "lockFile",
+			severity: SeverityError,
+		},
+		{
+			name:     "unknown git backend",
+			modify:   func(c *Config) { c.GitBackend = "libgit2" },
+			field:    "gitBackend",
+			severity: SeverityError,
+		},
+		{
+			name:     "negative push retries",
+			modify:   func(c *Config) { c.PushRetries = -1 },
+			field:    "pushRetries",
+			severity: SeverityError,
+		},
+		{
+			name:     "invalid push timeout",
+			modify:   func(c *Config) { c.PushTimeout = "soon" },
+			field:    "pushTimeout",
+			severity: SeverityError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			issues := c.Validate()
+			if tt.field == "" {
+				if len(issues) > 0 {
+					t.Fatalf("got issues %v", issues)
+				}
+				return
+			}
+			for _, issue := range issues {
+				if issue.Field == tt.field && issue.Severity == tt.severity {
+					if got := HasErrors(issues); got != (tt.severity == SeverityError) {
+						t.Errorf("HasErrors = %v", got)
+					}
+					return
+				}
+			}
+			t.Errorf("no %s for %s in %v", tt.severity, tt.field, issues)
+		})
+	}
+}
+
+func TestLoadConfigUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ai-docs.config.yml")
+	if err := os.WriteFile(path, []byte("userName: alice\nmainBranch: main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadConfig(path)
+	var unknown *UnknownKeyError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("got %v, want an UnknownKeyError", err)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ai-docs.config.yml")
+	if err := os.WriteFile(path, []byte("userName: alice\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MainBranchName != "main" || cfg.DocWorktreeDir != ".mem" || cfg.GetDocBranchName() != "@doc/alice" {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if issues := cfg.Validate(); HasErrors(issues) {
+		t.Errorf("default config has errors: %v", issues)
+	}
+}
+
+// Collection defaults apply only when the key is absent: a config listing
+// its own agent paths replaces the defaults instead of being merged into
+// them, and an empty map or list configures none.
+func TestLoadConfigCollectionDefaults(t *testing.T) {
+	defaults, err := LoadConfig(writeConfig(t, ".ai-docs.config.yml", "userName: alice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		file       string
+		content    string
+		wantPaths  map[string]string
+		wantIgnore []string
+	}{
+		{
+			name:       "absent",
+			file:       ".ai-docs.config.json",
+			content:    `{"userName": "alice"}`,
+			wantPaths:  defaults.AIAgentMemoryContextPath,
+			wantIgnore: defaults.IgnorePatterns,
+		},
+		{
+			name:       "own paths replace the defaults",
+			file:       ".ai-docs.config.yml",
+			content:    "aIAgentMemoryContextPath:\n  Claude: CLAUDE.md\nignorePatterns: [/CLAUDE.md]\n",
+			wantPaths:  map[string]string{"Claude": "CLAUDE.md"},
+			wantIgnore: []string{"/CLAUDE.md"},
+		},
+		{
+			name:       "empty yaml",
+			file:       ".ai-docs.config.yml",
+			content:    "aIAgentMemoryContextPath: {}\nignorePatterns: []\n",
+			wantPaths:  map[string]string{},
+			wantIgnore: []string{},
+		},
+		{
+			name:       "empty json",
+			file:       ".ai-docs.config.json",
+			content:    `{"aIAgentMemoryContextPath": {}, "ignorePatterns": []}`,
+			wantPaths:  map[string]string{},
+			wantIgnore: []string{},
+		},
+		{
+			name:       "empty toml",
+			file:       ".ai-docs.config.toml",
+			content:    "ignorePatterns = []\n[aIAgentMemoryContextPath]\n",
+			wantPaths:  map[string]string{},
+			wantIgnore: []string{},
+		},
+		{
+			name:       "agents",
+			file:       ".ai-docs.config.yml",
+			content:    "agents: [gemini]\n",
+			wantPaths:  map[string]string{"Gemini": "GEMINI.md"},
+			wantIgnore: []string{"/GEMINI.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg.AIAgentMemoryContextPath, tt.wantPaths) {
+				t.Errorf("paths = %v, want %v", cfg.AIAgentMemoryContextPath, tt.wantPaths)
+			}
+			if !reflect.DeepEqual(cfg.IgnorePatterns, tt.wantIgnore) {
+				t.Errorf("ignorePatterns = %#v, want %#v", cfg.IgnorePatterns, tt.wantIgnore)
+			}
+		})
+	}
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,481 @@
+// Package convert translates agent memory between the layouts used by
+// different AI coding agents: a single markdown file, a directory of Cursor
+// MDC rules, a directory of markdown rules and the Cline memory bank.
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/trknhr/ai-docs/markdown"
+	"github.com/trknhr/ai-docs/sources"
+)
+
+// Format is the on-disk layout of an agent's memory.
+type Format string
+
+const (
+	// Markdown is a single file with one "##" section per rule, as read by
+	// Claude, Gemini and Codex.
+	Markdown Format = "markdown"
+	// MDC is a directory of .mdc files with frontmatter, as read by Cursor.
+	MDC Format = "mdc"
+	// MarkdownDir is a directory with one .md file per rule.
+	MarkdownDir Format = "markdown-dir"
+	// MemoryBank is the Cline memory bank: a directory of .md files with
+	// the core files first.
+	MemoryBank Format = "memory-bank"
+)
+
+// Formats returns every supported format.
+func Formats() []Format {
+	return []Format{Markdown, MDC, MarkdownDir, MemoryBank}
+}
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats() {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// IsDir reports whether the format is a directory of files.
+func (f Format) IsDir() bool {
+	return f != Markdown
+}
+
+func (f Format) ext() string {
+	if f == MDC {
+		return ".mdc"
+	}
+	return ".md"
+}
+
+// Rule is one unit of agent memory: a section of a markdown file or one file
+// of a rules directory.
+type Rule struct {
+	// Name identifies the rule and names its file in directory formats.
+	Name  string
+	Title string
+	// Description tells the agent when the rule is relevant.
+	Description string
+	// Globs limit the rule to matching files; empty means all files.
+	Globs []string
+	// AlwaysApply is set for rules that are always in context.
+	AlwaysApply bool
+	// Body is the markdown content without the title heading.
+	Body string
+}
+
+// memoryBankFiles are the core files of the Cline memory bank, in the order
+// Cline reads them.
+var memoryBankFiles = []string{"projectbrief", "productContext", "systemPatterns", "techContext", "activeContext", "progress"}
+
+// Read reads the rules stored at path in format f.
+func Read(path string, f Format) ([]Rule, error) {
+	if !f.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return parseMarkdown(StripHeader(string(sources.Strip(data))), filepath.Base(path)), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != f.ext() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := sources.Header(data); ok {
+			continue
+		}
+		name := orderRe.ReplaceAllString(strings.TrimSuffix(e.Name(), f.ext()), "")
+		if f == MDC {
+			rules = append(rules, parseMDC(StripHeader(string(data)), name))
+		} else {
+			rules = append(rules, parseRuleFile(StripHeader(string(data)), name, true))
+		}
+	}
+	if f == MemoryBank {
+		sortMemoryBank(rules)
+	}
+	return rules, nil
+}
+
+// Render returns the files that store rules at path in format f, keyed by
+// file path.
+func Render(path string, f Format, rules []Rule) map[string][]byte {
+	files := map[string][]byte{}
+	if !f.IsDir() {
+		var b strings.Builder
+		for i, r := range rules {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			writeSection(&b, "## ", r, true)
+		}
+		files[path] = []byte(b.String())
+		return files
+	}
+
+	used := map[string]bool{}
+	for i, r := range rules {
+		name := uniqueName(fileName(r), used)
+		if f != MemoryBank {
+			// Number the files so that the rules keep their order.
+			name = fmt.Sprintf("%02d-%s", i+1, name)
+		}
+		var b strings.Builder
+		if f == MDC {
+			writeFrontmatter(&b, r)
+		}
+		writeSection(&b, "# ", r, f != MDC)
+		files[filepath.Join(path, name+f.ext())] = []byte(b.String())
+	}
+	return files
+}
+
+// Write stores rules at path in format f. Files whose content is unchanged
+// are left alone, and in directory formats other files of the format are
+// removed so that path holds exactly rules.
+func Write(path string, f Format, rules []Rule) (written, removed []string, err error) {
+	return WriteFiles(path, f, Render(path, f, rules))
+}
+
+// Existing returns the files of format f currently stored at path, leaving
+// out files copied from sources.
+func Existing(path string, f Format) ([]string, error) {
+	if !f.IsDir() {
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != f.ext() {
+			continue
+		}
+		file := filepath.Join(path, e.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := sources.Header(data); !ok {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// WriteFiles stores files rendered for path in format f, as Write does.
+func WriteFiles(path string, f Format, files map[string][]byte) (written, removed []string, err error) {
+	if f.IsDir() {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, nil, err
+		}
+		existing, err := Existing(path, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range existing {
+			if _, ok := files[file]; ok {
+				continue
+			}
+			if err := os.Remove(file); err != nil {
+				return written, removed, err
+			}
+			removed = append(removed, file)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for file := range files {
+		paths = append(paths, file)
+	}
+	sort.Strings(paths)
+	for _, file := range paths {
+		data := files[file]
+		old, err := os.ReadFile(file)
+		if err == nil {
+			// Keep what sources merged into a single file.
+			data = sources.Carry(old, data)
+			if bytes.Equal(old, data) {
+				continue
+			}
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return written, removed, err
+		}
+		written = append(written, file)
+	}
+	return written, removed, nil
+}
+
+// parseMarkdown splits a markdown file into one rule per "##" section. Text
+// before the first section becomes a rule of its own, titled by a leading
+// "#" heading if there is one.
+func parseMarkdown(text, file string) []Rule {
+	var (
+		rules   []Rule
+		title   string
+		lines   []string
+		fences  markdown.Fences
+		started bool
+	)
+	flush := func() {
+		body := strings.Join(lines, "\n")
+		if strings.TrimSpace(body) == "" {
+			return
+		}
+		r := parseRuleBody(body)
+		r.Title = title
+		rules = append(rules, r)
+	}
+
+	for _, line := range strings.Split(normalizeNewlines(text), "\n") {
+		if !fences.Next(line) {
+			if level, text, ok := markdown.Heading(line); ok {
+				switch {
+				case level == 1 && !started && strings.TrimSpace(strings.Join(lines, "")) == "":
+					title = text
+					started = true
+					continue
+				case level == 2:
+					flush()
+					title, lines, started = text, nil, true
+					continue
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	for i := range rules {
+		rules[i].Name = slug(rules[i].Title)
+		if rules[i].Name == "" {
+			rules[i].Name = slug(strings.TrimSuffix(file, filepath.Ext(file)))
+		}
+		rules[i].AlwaysApply = len(rules[i].Globs) == 0
+	}
+	return rules
+}
+
+// parseRuleFile parses a markdown file holding a single rule. With meta set,
+// the description and globs are read from the text.
+func parseRuleFile(text, name string, meta bool) Rule {
+	text = normalizeNewlines(text)
+	var title string
+	if first, rest, _ := strings.Cut(strings.TrimLeft(text, "\n"), "\n"); strings.HasPrefix(first, "# ") {
+		title = strings.TrimSpace(strings.TrimPrefix(first, "# "))
+		text = rest
+	}
+	r := Rule{Body: strings.Trim(text, "\n")}
+	if meta {
+		r = parseRuleBody(text)
+	}
+	r.Name = name
+	r.Title = title
+	r.AlwaysApply = len(r.Globs) == 0
+	return r
+}
+
+// parseMDC parses a Cursor rule. Cursor's frontmatter is not strict YAML,
+// e.g. globs may be an unquoted "*.ts", so it is read line by line.
+func parseMDC(text, name string) Rule {
+	text = normalizeNewlines(text)
+	var front string
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[4:], "\n---"); end >= 0 {
+			front = text[4 : 4+end]
+			text = strings.TrimPrefix(text[4+end+4:], "\n")
+		}
+	}
+
+	r := parseRuleFile(text, name, false)
+	r.AlwaysApply = false
+	for _, line := range strings.Split(front, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = unquote(strings.TrimSpace(value))
+		switch strings.TrimSpace(key) {
+		case "description":
+			r.Description = value
+		case "globs":
+			r.Globs = splitGlobs(value)
+		case "alwaysApply":
+			r.AlwaysApply = value == "true"
+		}
+	}
+	return r
+}
+
+const appliesPrefix = "Applies to: "
+
+// parseRuleBody reads the description ("> ...") and glob ("Applies to: ...")
+// lines that writeSection puts at the top of a rule.
+func parseRuleBody(body string) Rule {
+	var r Rule
+	lines := strings.Split(body, "\n")
+	i := 0
+	for {
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+		if i >= len(lines) {
+			break
+		}
+		line := strings.TrimSpace(lines[i])
+		if r.Description == "" && r.Globs == nil && strings.HasPrefix(line, "> ") && (i+1 == len(lines) || strings.TrimSpace(lines[i+1]) == "") {
+			r.Description = strings.TrimPrefix(line, "> ")
+			i++
+			continue
+		}
+		if r.Globs == nil && strings.HasPrefix(line, appliesPrefix) {
+			r.Globs = splitGlobs(strings.TrimPrefix(line, appliesPrefix))
+			i++
+			continue
+		}
+		break
+	}
+	r.Body = strings.Trim(strings.Join(lines[i:], "\n"), "\n")
+	return r
+}
+
+func writeFrontmatter(b *strings.Builder, r Rule) {
+	b.WriteString("---\n")
+	b.WriteString(strings.TrimRight("description: "+r.Description, " ") + "\n")
+	b.WriteString(strings.TrimRight("globs: "+strings.Join(r.Globs, ", "), " ") + "\n")
+	fmt.Fprintf(b, "alwaysApply: %t\n", r.AlwaysApply)
+	b.WriteString("---\n\n")
+}
+
+// writeSection writes r under heading. With meta set, the description and
+// globs are written as text, for formats without frontmatter.
+func writeSection(b *strings.Builder, heading string, r Rule, meta bool) {
+	title := r.Title
+	if title == "" {
+		title = r.Name
+	}
+	fmt.Fprintf(b, "%s%s\n\n", heading, title)
+	if meta {
+		if r.Description != "" {
+			fmt.Fprintf(b, "> %s\n\n", r.Description)
+		}
+		if len(r.Globs) > 0 {
+			quoted := make([]string, len(r.Globs))
+			for i, g := range r.Globs {
+				quoted[i] = "`" + g + "`"
+			}
+			fmt.Fprintf(b, "%s%s\n\n", appliesPrefix, strings.Join(quoted, ", "))
+		}
+	}
+	if body := strings.Trim(r.Body, "\n"); body != "" {
+		b.WriteString(body)
+		b.WriteString("\n")
+	}
+}
+
+// fileName returns the file name, without extension, of r in directory
+// formats.
+func fileName(r Rule) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if s := slug(r.Title); s != "" {
+		return s
+	}
+	return "rule"
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+func sortMemoryBank(rules []Rule) {
+	rank := func(name string) int {
+		for i, core := range memoryBankFiles {
+			if name == core {
+				return i
+			}
+		}
+		return len(memoryBankFiles)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		ri, rj := rank(rules[i].Name), rank(rules[j].Name)
+		if ri != rj {
+			return ri < rj
+		}
+		return rules[i].Name < rules[j].Name
+	})
+}
+
+var (
+	slugRe  = regexp.MustCompile(`[^a-z0-9]+`)
+	orderRe = regexp.MustCompile(`^\d+-`)
+)
+
+func slug(s string) string {
+	return strings.Trim(slugRe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+func splitGlobs(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	var globs []string
+	for _, g := range strings.Split(s, ",") {
+		g = strings.Trim(unquote(strings.TrimSpace(g)), "`")
+		if g != "" {
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
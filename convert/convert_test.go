@@ -0,0 +1,241 @@
+package convert
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/trknhr/ai-docs/sources"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		path   string
+		files  map[string]string
+		want   []Rule
+	}{
+		{
+			name:   "markdown sections",
+			format: Markdown,
+			path:   "CLAUDE.md",
+			files: map[string]string{
+				"CLAUDE.md": "# Project\n\nIntro text.\n\n## Go style\n\n> Go conventions\n\nApplies to: `*.go`, `go.mod`\n\nUse gofmt.\n\n## Tests\r\n\r\nRun go test.\r\n",
+			},
+			want: []Rule{
+				{Name: "project", Title: "Project", AlwaysApply: true, Body: "Intro text."},
+				{Name: "go-style", Title: "Go style", Description: "Go conventions", Globs: []string{"*.go", "go.mod"}, Body: "Use gofmt."},
+				{Name: "tests", Title: "Tests", AlwaysApply: true, Body: "Run go test."},
+			},
+		},
+		{
+			name:   "markdown without headings",
+			format: Markdown,
+			path:   "AGENTS.md",
+			files:  map[string]string{"AGENTS.md": "Just text.\n"},
+			want:   []Rule{{Name: "agents", AlwaysApply: true, Body: "Just text."}},
+		},
+		{
+			name:   "headings in code blocks",
+			format: Markdown,
+			path:   "CLAUDE.md",
+			files:  map[string]string{"CLAUDE.md": "## Shell\n\n```sh\n## not a section\n```\n"},
+			want:   []Rule{{Name: "shell", Title: "Shell", AlwaysApply: true, Body: "```sh\n## not a section\n```"}},
+		},
+		{
+			name:   "markdown drops source blocks and the generated header",
+			format: Markdown,
+			path:   "CLAUDE.md",
+			files: map[string]string{
+				"CLAUDE.md": string(AddHeader([]byte("## Mine\n\nx\n"), "fragments")) +
+					"\n<!-- " + sources.Marker + " org@1a2b3c4 -->\n## Theirs\ny\n" + sources.EndMarker + "\n",
+			},
+			want: []Rule{{Name: "mine", Title: "Mine", AlwaysApply: true, Body: "x"}},
+		},
+		{
+			name:   "mdc",
+			format: MDC,
+			path:   "rules",
+			files: map[string]string{
+				"rules/01-go.mdc":     "---\ndescription: Go conventions\nglobs: *.go, \"go.mod\"\nalwaysApply: false\n---\n\n# Go\n\nUse gofmt.\n",
+				"rules/02-always.mdc": "---\nalwaysApply: true\n---\nAlways.\n",
+				"rules/notes.txt":     "ignored\n",
+				"rules/sub/x.mdc":     "ignored\n",
+			},
+			want: []Rule{
+				{Name: "go", Title: "Go", Description: "Go conventions", Globs: []string{"*.go", "go.mod"}, Body: "Use gofmt."},
+				{Name: "always", AlwaysApply: true, Body: "Always."},
+			},
+		},
+		{
+			name:   "mdc skips files copied from sources",
+			format: MDC,
+			path:   "rules",
+			files: map[string]string{
+				"rules/mine.mdc":   "Mine.\n",
+				"rules/theirs.mdc": string(sources.AddHeader([]byte("Theirs.\n"), "org", "1a2b3c4d")),
+			},
+			want: []Rule{{Name: "mine", Body: "Mine."}},
+		},
+		{
+			name:   "markdown dir",
+			format: MarkdownDir,
+			path:   "rules",
+			files: map[string]string{
+				"rules/01-style.md": "# Style\n\n> Formatting\n\nUse gofmt.\n",
+			},
+			want: []Rule{{Name: "style", Title: "Style", Description: "Formatting", AlwaysApply: true, Body: "Use gofmt."}},
+		},
+		{
+			name:   "memory bank core files first",
+			format: MemoryBank,
+			path:   "memory-bank",
+			files: map[string]string{
+				"memory-bank/zz.md":            "Extra.\n",
+				"memory-bank/progress.md":      "Progress.\n",
+				"memory-bank/projectbrief.md":  "Brief.\n",
+				"memory-bank/activeContext.md": "Active.\n",
+			},
+			want: []Rule{
+				{Name: "projectbrief", AlwaysApply: true, Body: "Brief."},
+				{Name: "activeContext", AlwaysApply: true, Body: "Active."},
+				{Name: "progress", AlwaysApply: true, Body: "Progress."},
+				{Name: "zz", AlwaysApply: true, Body: "Extra."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			got, err := Read(filepath.Join(dir, tt.path), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got:\n%#v\nwant:\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	rules := []Rule{
+		{Name: "go", Title: "Go", Description: "Go conventions", Globs: []string{"*.go"}, Body: "Use gofmt."},
+		{Name: "go", Title: "Go tests", AlwaysApply: true, Body: "Run go test."},
+	}
+	tests := []struct {
+		format Format
+		want   map[string]string
+	}{
+		{
+			format: Markdown,
+			want: map[string]string{
+				"out": "## Go\n\n> Go conventions\n\nApplies to: `*.go`\n\nUse gofmt.\n\n## Go tests\n\nRun go test.\n",
+			},
+		},
+		{
+			format: MDC,
+			want: map[string]string{
+				"out/01-go.mdc":   "---\ndescription: Go conventions\nglobs: *.go\nalwaysApply: false\n---\n\n# Go\n\nUse gofmt.\n",
+				"out/02-go-2.mdc": "---\ndescription:\nglobs:\nalwaysApply: true\n---\n\n# Go tests\n\nRun go test.\n",
+			},
+		},
+		{
+			format: MarkdownDir,
+			want: map[string]string{
+				"out/01-go.md":   "# Go\n\n> Go conventions\n\nApplies to: `*.go`\n\nUse gofmt.\n",
+				"out/02-go-2.md": "# Go tests\n\nRun go test.\n",
+			},
+		},
+		{
+			format: MemoryBank,
+			want: map[string]string{
+				"out/go.md":   "# Go\n\n> Go conventions\n\nApplies to: `*.go`\n\nUse gofmt.\n",
+				"out/go-2.md": "# Go tests\n\nRun go test.\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got := map[string]string{}
+			for path, data := range Render("out", tt.format, rules) {
+				got[filepath.ToSlash(path)] = string(data)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRoundTrip converts rules through every format and back and checks
+// that nothing the target format can hold is lost.
+func TestRoundTrip(t *testing.T) {
+	rules := []Rule{
+		{Name: "go-style", Title: "Go style", Description: "Go conventions", Globs: []string{"*.go", "go.mod"}, Body: "Use gofmt.\n\n```go\n## not a heading\n```"},
+		{Name: "tests", Title: "Tests", AlwaysApply: true, Body: "Run go test."},
+	}
+	for _, f := range Formats() {
+		t.Run(string(f), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out")
+			if _, _, err := Write(path, f, rules); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(path, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, rules) {
+				t.Errorf("got:\n%#v\nwant:\n%#v", got, rules)
+			}
+
+			written, removed, err := Write(path, f, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(written) > 0 || len(removed) > 0 {
+				t.Errorf("unchanged rules rewrote %v and removed %v", written, removed)
+			}
+		})
+	}
+}
+
+func TestWriteRemovesStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"rules/old.mdc":    "Old.\n",
+		"rules/source.mdc": string(sources.AddHeader([]byte("Theirs.\n"), "org", "1a2b3c4d")),
+		"rules/notes.txt":  "kept\n",
+	})
+	path := filepath.Join(dir, "rules")
+	_, removed, err := Write(path, MDC, []Rule{{Name: "new", Body: "New."}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(path, "old.mdc")}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+	for _, name := range []string{"source.mdc", "notes.txt", "01-new.mdc"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package convert
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	"github.com/trknhr/ai-docs/agents"
+)
+
+// Fragment is a canonical piece of memory: a markdown file whose frontmatter
+// says which agents and files it applies to.
+type Fragment struct {
+	// Path is the fragment file, relative to the fragments directory.
+	Path string
+	Rule Rule
+	// Agents are the IDs of the agents the fragment is rendered for; empty
+	// means all.
+	Agents []string
+}
+
+type fragmentFrontmatter struct {
+	Title       string     `yaml:"title"`
+	Description string     `yaml:"description"`
+	AppliesTo   stringList `yaml:"applies-to"`
+	Agents      stringList `yaml:"agents"`
+}
+
+// stringList accepts a single string or a list of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err == nil {
+		*l = splitGlobs(s)
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// ReadFragments reads the .md fragments below dir, ordered by path.
+func ReadFragments(dir string) ([]Fragment, error) {
+	var fragments []Fragment
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".md" {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := parseFragment(string(data), filepath.ToSlash(rel))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fragments = append(fragments, f)
+		return nil
+	})
+	return fragments, err
+}
+
+func parseFragment(text, rel string) (Fragment, error) {
+	text = normalizeNewlines(text)
+	var front fragmentFrontmatter
+	if strings.HasPrefix(text, "---\n") {
+		end := strings.Index(text[4:], "\n---")
+		if end < 0 {
+			return Fragment{}, fmt.Errorf("frontmatter is not closed with ---")
+		}
+		if err := yaml.UnmarshalStrict([]byte(text[4:4+end]), &front); err != nil {
+			return Fragment{}, fmt.Errorf("invalid frontmatter: %w", err)
+		}
+		text = strings.TrimPrefix(text[4+end+4:], "\n")
+	}
+
+	base := orderRe.ReplaceAllString(strings.TrimSuffix(filepath.Base(rel), ".md"), "")
+	r := parseRuleFile(text, base, false)
+	if front.Title != "" {
+		r.Title = front.Title
+	}
+	if r.Title == "" {
+		r.Title = base
+	}
+	r.Name = slug(r.Title)
+	r.Description = front.Description
+	r.Globs = front.AppliesTo
+	r.AlwaysApply = len(r.Globs) == 0
+
+	var ids []string
+	for _, name := range front.Agents {
+		agent, ok := agents.Lookup(name)
+		if !ok {
+			return Fragment{}, fmt.Errorf("unknown agent %q; known agents: %s", name, strings.Join(agents.IDs(), ", "))
+		}
+		ids = append(ids, agent.ID)
+	}
+
+	return Fragment{Path: rel, Rule: r, Agents: ids}, nil
+}
+
+// For reports whether the fragment is rendered for agent.
+func (f Fragment) For(agent agents.Agent) bool {
+	if len(f.Agents) == 0 {
+		return true
+	}
+	for _, id := range f.Agents {
+		if id == agent.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package convert
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trknhr/ai-docs/agents"
+)
+
+func TestReadFragments(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"02-tests.md":    "Run go test.\n",
+		"01-go.md":       "---\ntitle: Go style\ndescription: Go conventions\napplies-to: \"*.go, go.mod\"\nagents: [Claude, cursor]\n---\n\nUse gofmt.\n",
+		"lang/rust.md":   "---\napplies-to:\n  - \"*.rs\"\n---\n# Rust\n\nUse rustfmt.\n",
+		"lang/notes.txt": "ignored\n",
+	})
+
+	got, err := ReadFragments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Fragment{
+		{
+			Path:   "01-go.md",
+			Rule:   Rule{Name: "go-style", Title: "Go style", Description: "Go conventions", Globs: []string{"*.go", "go.mod"}, Body: "Use gofmt."},
+			Agents: []string{"claude", "cursor"},
+		},
+		{
+			Path: "02-tests.md",
+			Rule: Rule{Name: "tests", Title: "tests", AlwaysApply: true, Body: "Run go test."},
+		},
+		{
+			Path: "lang/rust.md",
+			Rule: Rule{Name: "rust", Title: "Rust", Globs: []string{"*.rs"}, Body: "Use rustfmt."},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%#v\nwant:\n%#v", got, want)
+	}
+
+	claude, _ := agents.Lookup("claude")
+	gemini, _ := agents.Lookup("gemini")
+	if !got[0].For(claude) || got[0].For(gemini) || !got[1].For(gemini) {
+		t.Error("For does not follow the agents list")
+	}
+}
+
+func TestParseFragmentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{name: "unclosed frontmatter", text: "---\ntitle: x\n", wantErr: "not closed"},
+		{name: "unknown key", text: "---\ntitel: x\n---\n", wantErr: "invalid frontmatter"},
+		{name: "unknown agent", text: "---\nagents: nobody\n---\n", wantErr: "unknown agent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFragment(tt.text, "a.md")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGeneratedHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "plain", data: "## Go\n\nUse gofmt.\n"},
+		{name: "frontmatter", data: "---\nalwaysApply: true\n---\n\n# Go\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if generated, _ := CheckHeader([]byte(tt.data)); generated {
+				t.Fatal("plain file reported as generated")
+			}
+			marked := AddHeader([]byte(tt.data), filepath.Join("docs", "fragments"))
+			if generated, edited := CheckHeader(marked); !generated || edited {
+				t.Errorf("CheckHeader = %v, %v, want true, false:\n%s", generated, edited, marked)
+			}
+			if strings.HasPrefix(tt.data, "---\n") && !strings.HasPrefix(string(marked), "---\n") {
+				t.Errorf("header was put before the frontmatter:\n%s", marked)
+			}
+			if got := StripHeader(string(marked)); got != tt.data {
+				t.Errorf("StripHeader:\n%s\nwant:\n%s", got, tt.data)
+			}
+
+			edited := strings.Replace(string(marked), "Go", "Rust", 1)
+			if generated, edited := CheckHeader([]byte(edited)); !generated || !edited {
+				t.Errorf("CheckHeader after an edit = %v, %v, want true, true", generated, edited)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/trknhr/ai-docs/sources"
+)
+
+// GeneratedMarker identifies the header of files written by `ai-docs build`.
+const GeneratedMarker = "ai-docs:generated"
+
+var headerRe = regexp.MustCompile(`(?m)^<!-- ` + GeneratedMarker + ` .*\(sha256:([0-9a-f]+)\) -->\n`)
+
+// AddHeader adds a "do not edit" header to data, a file generated from
+// source. The header records a hash of data so that CheckHeader can tell if
+// the file was edited afterwards. In files with frontmatter the header
+// follows the frontmatter, which agents expect at the top.
+func AddHeader(data []byte, source string) []byte {
+	text := string(data)
+	header := fmt.Sprintf("<!-- %s from %s by 'ai-docs build' - do not edit (sha256:%s) -->\n\n", GeneratedMarker, source, contentHash(text))
+
+	at := 0
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[4:], "\n---\n"); end >= 0 {
+			at = 4 + end + len("\n---\n")
+			if strings.HasPrefix(text[at:], "\n") {
+				at++
+			}
+		}
+	}
+	return []byte(text[:at] + header + text[at:])
+}
+
+// StripHeader removes the header added by AddHeader, if any.
+func StripHeader(text string) string {
+	loc := headerRe.FindStringIndex(text)
+	if loc == nil {
+		return text
+	}
+	end := loc[1]
+	if strings.HasPrefix(text[end:], "\n") {
+		end++
+	}
+	return text[:loc[0]] + text[end:]
+}
+
+// CheckHeader reports whether data carries a header added by AddHeader and,
+// if so, whether the file was changed since it was generated.
+func CheckHeader(data []byte) (generated, edited bool) {
+	text := string(sources.Strip(data))
+	m := headerRe.FindStringSubmatch(text)
+	if m == nil {
+		return false, false
+	}
+	return true, contentHash(StripHeader(text)) != m[1]
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,213 @@
+// Package include expands and collapses include directives in memory files.
+//
+// A directive is an HTML comment on a line of its own:
+//
+//	<!-- ai-docs:include shared/go.md#Error handling -->
+//
+// Expand inserts the referenced file, or one section of it, after the
+// directive and closes the block with an end marker. Collapse removes the
+// inserted text again so that only the directive is stored.
+package include
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/trknhr/ai-docs/markdown"
+)
+
+const (
+	// Marker starts an include directive.
+	Marker = "ai-docs:include"
+	// EndMarker closes the text inserted for a directive.
+	EndMarker = "<!-- ai-docs:end-include -->"
+)
+
+// maxDepth bounds nested includes.
+const maxDepth = 8
+
+var directiveRe = regexp.MustCompile(`^\s*<!--\s*` + Marker + `\s+(.+?)\s*-->\s*$`)
+
+// Directive is a parsed include directive.
+type Directive struct {
+	// Target is a path or URL; what it refers to is up to the Resolver.
+	Target string
+	// Section is the heading of the section to include; empty means the
+	// whole file.
+	Section string
+}
+
+func (d Directive) String() string {
+	if d.Section == "" {
+		return d.Target
+	}
+	return d.Target + "#" + d.Section
+}
+
+// Resolver returns the content of target.
+type Resolver func(ctx context.Context, target string) ([]byte, error)
+
+// Has reports whether data contains an include directive.
+func Has(data []byte) bool {
+	return bytes.Contains(data, []byte(Marker))
+}
+
+func parseDirective(line string) (Directive, bool) {
+	m := directiveRe.FindStringSubmatch(line)
+	if m == nil {
+		return Directive{}, false
+	}
+	d := Directive{Target: m[1]}
+	if i := strings.LastIndex(m[1], "#"); i >= 0 {
+		d.Target, d.Section = m[1][:i], strings.TrimSpace(m[1][i+1:])
+	}
+	return d, d.Target != ""
+}
+
+// Expand replaces the text after every directive in data with the current
+// content of its target, resolving nested directives too. Directives that
+// cannot be resolved are left as they are and reported in errs.
+func Expand(ctx context.Context, data []byte, resolve Resolver) (out []byte, errs []error) {
+	return expand(ctx, data, resolve, nil, &errs), errs
+}
+
+func expand(ctx context.Context, data []byte, resolve Resolver, stack []string, errs *[]error) []byte {
+	lines := markdown.SplitLines(string(data))
+	ends := blocks(lines)
+	var b strings.Builder
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		b.WriteString(line)
+		end, ok := ends[i]
+		if !ok {
+			continue
+		}
+		d, _ := parseDirective(line)
+		// Replace the text inserted by an earlier expansion.
+		start := i
+		if end >= 0 {
+			i = end
+		}
+
+		content, err := resolveDirective(ctx, d, resolve, stack, errs)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("include %s: %w", d, err))
+			// Keep the previous expansion, if any.
+			b.WriteString(strings.Join(lines[start+1:i+1], ""))
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteString("\n")
+		}
+		if content = strings.TrimRight(content, "\n"); content != "" {
+			b.WriteString(content)
+			b.WriteString("\n")
+		}
+		b.WriteString(EndMarker)
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+func resolveDirective(ctx context.Context, d Directive, resolve Resolver, stack []string, errs *[]error) (string, error) {
+	for _, t := range stack {
+		if t == d.Target {
+			return "", fmt.Errorf("include cycle: %s", strings.Join(append(stack, d.Target), " -> "))
+		}
+	}
+	if len(stack) >= maxDepth {
+		return "", fmt.Errorf("includes nested deeper than %d levels", maxDepth)
+	}
+
+	data, err := resolve(ctx, d.Target)
+	if err != nil {
+		return "", err
+	}
+	// Included files are stored collapsed, but may be expanded locally.
+	data = Collapse(data)
+	if d.Section != "" {
+		if data, err = Section(data, d.Section); err != nil {
+			return "", err
+		}
+	}
+	if Has(data) {
+		data = expand(ctx, data, resolve, append(stack, d.Target), errs)
+	}
+	return string(data), nil
+}
+
+// Collapse removes the text inserted by Expand, keeping the directives.
+func Collapse(data []byte) []byte {
+	if !Has(data) {
+		return data
+	}
+	lines := markdown.SplitLines(string(data))
+	ends := blocks(lines)
+	var b strings.Builder
+	for i := 0; i < len(lines); i++ {
+		b.WriteString(lines[i])
+		if end, ok := ends[i]; ok && end >= 0 {
+			i = end
+		}
+	}
+	return []byte(b.String())
+}
+
+// blocks finds the directives in lines, outside fenced code, and returns
+// the index of the end marker that closes the text inserted for each, or
+// -1 if it is not expanded, by the index of the directive. An end marker
+// closes the nearest directive before it that is still open, so blocks
+// nest; directives left open are not expanded.
+func blocks(lines []string) map[int]int {
+	ends := map[int]int{}
+	var open []int
+	var fences markdown.Fences
+	for i, line := range lines {
+		if fences.Next(line) {
+			continue
+		}
+		if strings.TrimSpace(line) == EndMarker {
+			if n := len(open); n > 0 {
+				ends[open[n-1]] = i
+				open = open[:n-1]
+			}
+			continue
+		}
+		if _, ok := parseDirective(line); ok {
+			ends[i] = -1
+			open = append(open, i)
+		}
+	}
+	return ends
+}
+
+// Section returns the section of markdown data under the heading named
+// name, including the heading, up to the next heading of the same or a
+// higher level. Names match case-insensitively.
+func Section(data []byte, name string) ([]byte, error) {
+	lines := markdown.SplitLines(string(data))
+	start, level := -1, 0
+	var fences markdown.Fences
+	for i, line := range lines {
+		if fences.Next(line) {
+			continue
+		}
+		l, text, ok := markdown.Heading(line)
+		if !ok {
+			continue
+		}
+		if start >= 0 && l <= level {
+			return []byte(strings.Join(lines[start:i], "")), nil
+		}
+		if start < 0 && strings.EqualFold(text, name) {
+			start, level = i, l
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no section %q", name)
+	}
+	return []byte(strings.Join(lines[start:], "")), nil
+}
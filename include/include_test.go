@@ -0,0 +1,264 @@
+package include
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func resolver(files map[string]string) Resolver {
+	return func(ctx context.Context, target string) ([]byte, error) {
+		data, ok := files[target]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return []byte(data), nil
+	}
+}
+
+func directive(target string) string {
+	return "<!-- " + Marker + " " + target + " -->\n"
+}
+
+func TestExpand(t *testing.T) {
+	files := map[string]string{
+		"a.md":      "A text\n",
+		"b.md":      "# B\n\n## One\none\n\n## Two\ntwo\n",
+		"nested.md": "before\n" + directive("a.md") + "after\n",
+		"cycle.md":  directive("cycle.md"),
+		"empty.md":  "",
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+		errs int
+	}{
+		{
+			name: "no directives",
+			in:   "# Title\n",
+			want: "# Title\n",
+		},
+		{
+			name: "whole file",
+			in:   "x\n" + directive("a.md") + "y\n",
+			want: "x\n" + directive("a.md") + "A text\n" + EndMarker + "\ny\n",
+		},
+		{
+			name: "section",
+			in:   directive("b.md#one"),
+			want: directive("b.md#one") + "## One\none\n" + EndMarker + "\n",
+		},
+		{
+			name: "replaces an earlier expansion",
+			in:   directive("a.md") + "stale\n" + EndMarker + "\n",
+			want: directive("a.md") + "A text\n" + EndMarker + "\n",
+		},
+		{
+			name: "nested",
+			in:   directive("nested.md"),
+			want: directive("nested.md") + "before\n" + directive("a.md") + "A text\n" + EndMarker + "\nafter\n" + EndMarker + "\n",
+		},
+		{
+			name: "empty file",
+			in:   directive("empty.md"),
+			want: directive("empty.md") + EndMarker + "\n",
+		},
+		{
+			name: "missing target keeps the directive",
+			in:   directive("missing.md") + "z\n",
+			want: directive("missing.md") + "z\n",
+			errs: 1,
+		},
+		{
+			name: "missing target keeps the previous expansion",
+			in:   directive("missing.md") + "old\n" + EndMarker + "\n",
+			want: directive("missing.md") + "old\n" + EndMarker + "\n",
+			errs: 1,
+		},
+		{
+			name: "missing section",
+			in:   directive("b.md#three"),
+			want: directive("b.md#three"),
+			errs: 1,
+		},
+		{
+			name: "cycle",
+			in:   directive("cycle.md"),
+			want: directive("cycle.md") + directive("cycle.md") + EndMarker + "\n",
+			errs: 1,
+		},
+		{
+			name: "inside a fence",
+			in:   "```\n" + directive("a.md") + "```\n",
+			want: "```\n" + directive("a.md") + "```\n",
+		},
+		{
+			name: "no trailing newline",
+			in:   strings.TrimSuffix(directive("a.md"), "\n"),
+			want: directive("a.md") + "A text\n" + EndMarker + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := Expand(context.Background(), []byte(tt.in), resolver(files))
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if len(errs) != tt.errs {
+				t.Errorf("got %d errors %v, want %d", len(errs), errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "no directives",
+			in:   "text\n",
+			want: "text\n",
+		},
+		{
+			name: "expanded",
+			in:   "x\n" + directive("a.md") + "A\n" + EndMarker + "\ny\n",
+			want: "x\n" + directive("a.md") + "y\n",
+		},
+		{
+			name: "not expanded",
+			in:   directive("a.md") + "y\n",
+			want: directive("a.md") + "y\n",
+		},
+		{
+			name: "nested",
+			in:   directive("n.md") + "b\n" + directive("a.md") + "A\n" + EndMarker + "\n" + EndMarker + "\nz\n",
+			want: directive("n.md") + "z\n",
+		},
+		{
+			name: "collapsed directive before an expanded one",
+			in:   directive("x.md") + "keep\n" + directive("a.md") + "A\n" + EndMarker + "\n",
+			want: directive("x.md") + "keep\n" + directive("a.md"),
+		},
+		{
+			name: "stray end marker",
+			in:   "a\n" + EndMarker + "\n",
+			want: "a\n" + EndMarker + "\n",
+		},
+		{
+			name: "end marker inside a fence",
+			in:   directive("a.md") + "```\n" + EndMarker + "\n```\n" + EndMarker + "\n",
+			want: directive("a.md"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Collapse([]byte(tt.in))); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandCollapseRoundTrip(t *testing.T) {
+	files := map[string]string{"a.md": "A\n", "b.md": "B\n" + directive("a.md")}
+	in := "# Memory\n" + directive("a.md") + "text\n" + directive("b.md")
+	expanded, errs := Expand(context.Background(), []byte(in), resolver(files))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got := string(Collapse(expanded)); got != in {
+		t.Errorf("got:\n%s\nwant:\n%s", got, in)
+	}
+	again, _ := Expand(context.Background(), expanded, resolver(files))
+	if string(again) != string(expanded) {
+		t.Errorf("expanding twice changed the text:\n%s", again)
+	}
+}
+
+func TestManyDirectives(t *testing.T) {
+	const n = 2000
+	files := map[string]string{}
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("f%d.md", i)
+		files[name] = name + "\n"
+		b.WriteString(directive(name))
+	}
+	collapsed := b.String()
+
+	start := time.Now()
+	expanded, errs := Expand(context.Background(), []byte(collapsed), resolver(files))
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	if got := strings.Count(string(expanded), EndMarker); got != n {
+		t.Errorf("got %d end markers, want %d", got, n)
+	}
+	if got := string(Collapse(expanded)); got != collapsed {
+		t.Error("Collapse did not restore the directives")
+	}
+	if got := string(Collapse([]byte(collapsed))); got != collapsed {
+		t.Error("Collapse changed collapsed text")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("took %s for %d directives", d, n)
+	}
+}
+
+func TestSection(t *testing.T) {
+	doc := "# Title\nintro\n## Go\ngo text\n### Errors\nwrap them\n## Rust\nrust\n```\n## Not a heading\n```\n"
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "Go", want: "## Go\ngo text\n### Errors\nwrap them\n"},
+		{name: "errors", want: "### Errors\nwrap them\n"},
+		{name: "Rust", want: "## Rust\nrust\n```\n## Not a heading\n```\n"},
+		{name: "Title", want: doc},
+		{name: "Not a heading", wantErr: true},
+		{name: "Missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Section([]byte(doc), tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	tests := []struct {
+		line string
+		want Directive
+		ok   bool
+	}{
+		{"<!-- ai-docs:include a.md -->", Directive{Target: "a.md"}, true},
+		{"  <!--ai-docs:include   shared/go.md#Error handling   -->\n", Directive{Target: "shared/go.md", Section: "Error handling"}, true},
+		{"<!-- ai-docs:include https://x/y.md#a#b -->", Directive{Target: "https://x/y.md#a", Section: "b"}, true},
+		{"<!-- ai-docs:include #only -->", Directive{}, false},
+		{"text <!-- ai-docs:include a.md -->", Directive{}, false},
+		{"<!-- ai-docs:end-include -->", Directive{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDirective(tt.line)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseDirective(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Package markdown has the line-level markdown parsing shared by convert,
+// include and sources: ATX headings and fenced code blocks.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fenceRe   = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// Heading parses line, with or without its newline, as an ATX heading and
+// returns its level and text.
+func Heading(line string) (level int, text string, ok bool) {
+	m := headingRe.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if m == nil {
+		return 0, "", false
+	}
+	return len(m[1]), m[2], true
+}
+
+// Fences tracks fenced code blocks over the lines of a file. The zero value
+// is outside any block.
+type Fences struct {
+	marker string
+}
+
+// Next records line and reports whether it belongs to a fenced code block,
+// counting the opening and closing fences. A block is closed by a fence of
+// the same kind as the one that opened it.
+func (f *Fences) Next(line string) bool {
+	m := fenceRe.FindStringSubmatch(line)
+	switch {
+	case f.marker == "" && m != nil:
+		f.marker = m[1]
+	case f.marker != "" && m != nil && m[1] == f.marker:
+		f.marker = ""
+	case f.marker == "":
+		return false
+	}
+	return true
+}
+
+// SplitLines splits s after each newline, keeping the newlines.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,94 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHeading(t *testing.T) {
+	tests := []struct {
+		line  string
+		level int
+		text  string
+		ok    bool
+	}{
+		{"# Title", 1, "Title", true},
+		{"## Section\n", 2, "Section", true},
+		{"### Closed ###\r\n", 3, "Closed", true},
+		{"###### Six", 6, "Six", true},
+		{"####### Seven", 0, "", false},
+		{"#NoSpace", 0, "", false},
+		{"text # not a heading", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		level, text, ok := Heading(tt.line)
+		if level != tt.level || text != tt.text || ok != tt.ok {
+			t.Errorf("Heading(%q) = %d, %q, %v, want %d, %q, %v", tt.line, level, text, ok, tt.level, tt.text, tt.ok)
+		}
+	}
+}
+
+func TestFences(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []bool
+	}{
+		{
+			name:  "no fences",
+			lines: []string{"a", "# b"},
+			want:  []bool{false, false},
+		},
+		{
+			name:  "backticks",
+			lines: []string{"a", "```go", "# b", "```", "c"},
+			want:  []bool{false, true, true, true, false},
+		},
+		{
+			name:  "tildes inside backticks",
+			lines: []string{"```", "~~~", "# b", "```", "# c"},
+			want:  []bool{true, true, true, true, false},
+		},
+		{
+			name:  "indented",
+			lines: []string{"  ~~~", "x", "  ~~~\n", "y"},
+			want:  []bool{true, true, true, false},
+		},
+		{
+			name:  "unclosed",
+			lines: []string{"```", "a", "b"},
+			want:  []bool{true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f Fences
+			var got []bool
+			for _, line := range tt.lines {
+				got = append(got, f.Next(line))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a\n"}},
+		{"a\nb", []string{"a\n", "b"}},
+		{"a\n\nb\n", []string{"a\n", "\n", "b\n"}},
+	}
+	for _, tt := range tests {
+		if got := SplitLines(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package redact replaces sensitive values in memory files with placeholders
+// and puts them back again.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IndexVerb in a placeholder is replaced with a number that is distinct for
+// every value the rule matches, e.g. "{{HOST_{n}}}" becomes "{{HOST_1}}".
+const IndexVerb = "{n}"
+
+// Rule replaces every match of Regex with Placeholder.
+type Rule struct {
+	Regex       *regexp.Regexp
+	Placeholder string
+}
+
+// Mapping records which value each placeholder stands for. It is kept
+// outside the doc branch so the values never leave the machine.
+type Mapping struct {
+	Values map[string]string `json:"values"`
+	// Ambiguous lists placeholders that stood for more than one value and
+	// therefore cannot be expanded.
+	Ambiguous []string `json:"ambiguous,omitempty"`
+
+	path    string
+	changed bool
+}
+
+// LoadMapping reads the mapping file at path. A missing file yields an empty
+// mapping.
+func LoadMapping(path string) (*Mapping, error) {
+	m := &Mapping{Values: map[string]string{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction mapping %s: %w", path, err)
+	}
+	if m.Values == nil {
+		m.Values = map[string]string{}
+	}
+	return m, nil
+}
+
+// Save writes the mapping back if it changed. The file is only readable by
+// the current user.
+func (m *Mapping) Save() error {
+	if !m.changed {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.path, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	m.changed = false
+	return nil
+}
+
+func (m *Mapping) ambiguous(placeholder string) bool {
+	for _, p := range m.Ambiguous {
+		if p == placeholder {
+			return true
+		}
+	}
+	return false
+}
+
+// placeholderFor returns the placeholder for value matched by rule and
+// records it.
+func (m *Mapping) placeholderFor(rule Rule, value string) string {
+	if !strings.Contains(rule.Placeholder, IndexVerb) {
+		p := rule.Placeholder
+		if old, ok := m.Values[p]; ok && old != value {
+			delete(m.Values, p)
+			m.Ambiguous = append(m.Ambiguous, p)
+			m.changed = true
+		} else if !ok && !m.ambiguous(p) {
+			m.Values[p] = value
+			m.changed = true
+		}
+		return p
+	}
+
+	for n := 1; ; n++ {
+		p := strings.ReplaceAll(rule.Placeholder, IndexVerb, strconv.Itoa(n))
+		old, ok := m.Values[p]
+		if ok && old == value {
+			return p
+		}
+		if !ok {
+			m.Values[p] = value
+			m.changed = true
+			return p
+		}
+	}
+}
+
+// Redactor applies rules to file contents.
+type Redactor struct {
+	rules   []Rule
+	mapping *Mapping
+}
+
+// New returns a Redactor for rules that records placeholders in mapping.
+func New(rules []Rule, mapping *Mapping) *Redactor {
+	return &Redactor{rules: rules, mapping: mapping}
+}
+
+// Redact is a utils.Transform that replaces every value matched by a rule
+// with its placeholder.
+func (r *Redactor) Redact(dst string, data []byte) ([]byte, error) {
+	for _, rule := range r.rules {
+		data = rule.Regex.ReplaceAllFunc(data, func(match []byte) []byte {
+			return []byte(r.mapping.placeholderFor(rule, string(match)))
+		})
+	}
+	return data, nil
+}
+
+// Expand is a utils.Transform that replaces placeholders with the values
+// recorded in the mapping. Ambiguous placeholders are left as they are.
+func (r *Redactor) Expand(dst string, data []byte) ([]byte, error) {
+	if len(r.mapping.Values) == 0 {
+		return data, nil
+	}
+
+	// Longest placeholders first, so that "{{HOST_10}}" is not expanded
+	// as "{{HOST_1}}" followed by "0}}".
+	placeholders := make([]string, 0, len(r.mapping.Values))
+	for p := range r.mapping.Values {
+		placeholders = append(placeholders, p)
+	}
+	sort.Slice(placeholders, func(i, j int) bool {
+		if len(placeholders[i]) != len(placeholders[j]) {
+			return len(placeholders[i]) > len(placeholders[j])
+		}
+		return placeholders[i] < placeholders[j]
+	})
+
+	pairs := make([]string, 0, 2*len(placeholders))
+	for _, p := range placeholders {
+		pairs = append(pairs, p, r.mapping.Values[p])
+	}
+	var buf bytes.Buffer
+	if _, err := strings.NewReplacer(pairs...).WriteString(&buf, string(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package redact
+
+import (
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func testRules() []Rule {
+	return []Rule{
+		{Regex: regexp.MustCompile(`[a-z0-9-]+\.internal\.example\.com`), Placeholder: "{{HOST_{n}}}"},
+		{Regex: regexp.MustCompile(`acct-[0-9]{6}`), Placeholder: "{{ACCOUNT}}"},
+	}
+}
+
+func TestRedactRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		redacted string
+	}{
+		{
+			name:     "nothing to redact",
+			data:     "# Notes\n",
+			redacted: "# Notes\n",
+		},
+		{
+			name:     "indexed placeholders",
+			data:     "db.internal.example.com and cache.internal.example.com, again db.internal.example.com\n",
+			redacted: "{{HOST_1}} and {{HOST_2}}, again {{HOST_1}}\n",
+		},
+		{
+			name:     "fixed placeholder",
+			data:     "account acct-123456\n",
+			redacted: "account {{ACCOUNT}}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(testRules(), &Mapping{Values: map[string]string{}})
+			redacted, err := r.Redact("a.md", []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(redacted) != tt.redacted {
+				t.Errorf("redacted:\n%s\nwant:\n%s", redacted, tt.redacted)
+			}
+			expanded, err := r.Expand("a.md", redacted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(expanded) != tt.data {
+				t.Errorf("expanded:\n%s\nwant:\n%s", expanded, tt.data)
+			}
+		})
+	}
+}
+
+func TestAmbiguousPlaceholder(t *testing.T) {
+	r := New(testRules(), &Mapping{Values: map[string]string{}})
+	redacted, err := r.Redact("a.md", []byte("acct-111111 acct-222222\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{{ACCOUNT}} {{ACCOUNT}}\n"; string(redacted) != want {
+		t.Errorf("got %q, want %q", redacted, want)
+	}
+	if !reflect.DeepEqual(r.mapping.Ambiguous, []string{"{{ACCOUNT}}"}) {
+		t.Errorf("Ambiguous = %v", r.mapping.Ambiguous)
+	}
+	expanded, _ := r.Expand("a.md", redacted)
+	if string(expanded) != string(redacted) {
+		t.Errorf("an ambiguous placeholder was expanded: %q", expanded)
+	}
+
+	// A later value must not make the placeholder expandable again.
+	if _, err := r.Redact("b.md", []byte("acct-333333\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.mapping.Values["{{ACCOUNT}}"]; ok {
+		t.Error("ambiguous placeholder was recorded again")
+	}
+}
+
+func TestExpandLongestFirst(t *testing.T) {
+	m := &Mapping{Values: map[string]string{"{{HOST_1}}": "one", "{{HOST_10}}": "ten"}}
+	got, err := New(nil, m).Expand("a.md", []byte("{{HOST_10}} {{HOST_1}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ten one" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestMappingSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redact", "mapping.json")
+	m, err := LoadMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(testRules(), m).Redact("a.md", []byte("db.internal.example.com\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Values, map[string]string{"{{HOST_1}}": "db.internal.example.com"}) {
+		t.Errorf("loaded %v", loaded.Values)
+	}
+	redacted, _ := New(testRules(), loaded).Redact("b.md", []byte("db.internal.example.com\n"))
+	if string(redacted) != "{{HOST_1}}\n" {
+		t.Errorf("reloaded mapping gave %q", redacted)
+	}
+}
@@ -0,0 +1,110 @@
+package secrets
+
+import "regexp"
+
+// Rule matches one kind of credential.
+type Rule struct {
+	// ID names the rule in findings and in allowlists, e.g. "github-token".
+	ID          string
+	Description string
+	Regex       *regexp.Regexp
+	// SecretGroup is the capture group holding the secret itself; 0 means
+	// the whole match.
+	SecretGroup int
+	// Entropy is the minimum Shannon entropy, in bits per character, the
+	// secret must have. It filters out placeholders such as "changeme" for
+	// rules that match generic assignments.
+	Entropy float64
+}
+
+// DefaultRules returns the built-in rules for common credential formats.
+func DefaultRules() []Rule {
+	return []Rule{
+		{
+			ID:          "private-key",
+			Description: "Private key",
+			Regex:       regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`),
+		},
+		{
+			ID:          "age-secret-key",
+			Description: "age secret key",
+			Regex:       regexp.MustCompile(`AGE-SECRET-KEY-1[0-9A-Z]{58}`),
+		},
+		{
+			ID:          "aws-access-key-id",
+			Description: "AWS access key ID",
+			Regex:       regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`),
+		},
+		{
+			ID:          "aws-secret-access-key",
+			Description: "AWS secret access key",
+			Regex:       regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+			SecretGroup: 1,
+			Entropy:     4,
+		},
+		{
+			ID:          "github-token",
+			Description: "GitHub token",
+			Regex:       regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{82})\b`),
+		},
+		{
+			ID:          "gitlab-token",
+			Description: "GitLab personal access token",
+			Regex:       regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`),
+		},
+		{
+			ID:          "slack-token",
+			Description: "Slack token",
+			Regex:       regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`),
+		},
+		{
+			ID:          "slack-webhook",
+			Description: "Slack webhook URL",
+			Regex:       regexp.MustCompile(`https://hooks\.slack\.com/(?:services|workflows)/[A-Za-z0-9+/]{20,}`),
+		},
+		{
+			ID:          "stripe-key",
+			Description: "Stripe secret key",
+			Regex:       regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}\b`),
+		},
+		{
+			ID:          "google-api-key",
+			Description: "Google API key",
+			Regex:       regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+		},
+		{
+			ID:          "anthropic-api-key",
+			Description: "Anthropic API key",
+			Regex:       regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`),
+		},
+		{
+			ID:          "openai-api-key",
+			Description: "OpenAI API key",
+			Regex:       regexp.MustCompile(`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}T3BlbkFJ[A-Za-z0-9_-]{20,}|\bsk-proj-[A-Za-z0-9_-]{40,}`),
+		},
+		{
+			ID:          "npm-token",
+			Description: "npm access token",
+			Regex:       regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`),
+		},
+		{
+			ID:          "jwt",
+			Description: "JSON Web Token",
+			Regex:       regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+		},
+		{
+			ID:          "url-credentials",
+			Description: "Password in URL",
+			Regex:       regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:([^/\s:@]{6,})@`),
+			SecretGroup: 1,
+			Entropy:     3,
+		},
+		{
+			ID:          "generic-secret",
+			Description: "Secret assigned to a key, token or password",
+			Regex:       regexp.MustCompile(`(?i)\b(?:api[_-]?key|secret|token|passw(?:or)?d|credentials?|auth)\b["']?\s*[:=]\s*["']?([A-Za-z0-9_\-/+=.~]{16,})`),
+			SecretGroup: 1,
+			Entropy:     3.5,
+		},
+	}
+}
@@ -0,0 +1,204 @@
+// Package secrets finds credentials in memory files before they are pushed.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// AllowComment on a line suppresses findings on that line.
+const AllowComment = "ai-docs:allow-secret"
+
+// Finding is a possible secret found by a Rule.
+type Finding struct {
+	File   string
+	Line   int
+	Column int
+	Rule   Rule
+	Secret string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s): %s", f.File, f.Line, f.Column, f.Rule.Description, f.Rule.ID, mask(f.Secret))
+}
+
+// Allowlist suppresses findings.
+type Allowlist struct {
+	// Regexes are matched against the secret of a finding.
+	Regexes []*regexp.Regexp
+	// Paths are slash-separated glob patterns; a pattern also matches
+	// everything below a directory it matches.
+	Paths []string
+	// Rules are rule IDs that are not reported.
+	Rules []string
+}
+
+// Scanner checks files against a set of rules.
+type Scanner struct {
+	rules []Rule
+	allow Allowlist
+}
+
+func NewScanner(rules []Rule, allow Allowlist) *Scanner {
+	return &Scanner{rules: rules, allow: allow}
+}
+
+// Scan returns the findings in data, which was read from name.
+func (s *Scanner) Scan(name string, data []byte) []Finding {
+	if s.pathAllowed(name) {
+		return nil
+	}
+
+	var findings []Finding
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := sc.Text()
+		if strings.Contains(line, AllowComment) {
+			continue
+		}
+		// Rules are ordered from specific to generic; a secret already
+		// reported on this line is not reported again by a later rule.
+		var spans [][2]int
+		for _, rule := range s.rules {
+			if s.ruleAllowed(rule.ID) {
+				continue
+			}
+			for _, m := range rule.Regex.FindAllStringSubmatchIndex(line, -1) {
+				start, end := m[0], m[1]
+				if g := rule.SecretGroup; g > 0 && 2*g+1 < len(m) && m[2*g] >= 0 {
+					start, end = m[2*g], m[2*g+1]
+				}
+				secret := line[start:end]
+				if rule.Entropy > 0 && entropy(secret) < rule.Entropy {
+					continue
+				}
+				if s.secretAllowed(secret) || overlaps(spans, start, end) {
+					continue
+				}
+				spans = append(spans, [2]int{start, end})
+				findings = append(findings, Finding{
+					File:   name,
+					Line:   lineNo,
+					Column: start + 1,
+					Rule:   rule,
+					Secret: secret,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// ScanPaths scans every file under paths. Paths that do not exist are
+// skipped. read returns the content to scan for a file, which lets callers
+// scan content after their own transformations; nil reads the file as is.
+func (s *Scanner) ScanPaths(ctx context.Context, paths []string, read func(file string) ([]byte, error)) ([]Finding, error) {
+	if read == nil {
+		read = os.ReadFile
+	}
+
+	var findings []Finding
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		err := filepath.Walk(p, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			data, err := read(file)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, s.Scan(filepath.ToSlash(file), data)...)
+			return nil
+		})
+		if err != nil {
+			return findings, fmt.Errorf("failed to scan %s: %w", p, err)
+		}
+	}
+	return findings, nil
+}
+
+func (s *Scanner) pathAllowed(name string) bool {
+	name = path.Clean(filepath.ToSlash(name))
+	for _, pattern := range s.allow.Paths {
+		pattern = strings.TrimSuffix(path.Clean(filepath.ToSlash(pattern)), "/")
+		for p := name; p != "." && p != "/"; p = path.Dir(p) {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Scanner) ruleAllowed(id string) bool {
+	for _, r := range s.allow.Rules {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) secretAllowed(secret string) bool {
+	for _, re := range s.allow.Regexes {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(spans [][2]int, start, end int) bool {
+	for _, sp := range spans {
+		if start < sp[1] && sp[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// mask hides all but the start of secret so findings can be printed safely.
+func mask(secret string) string {
+	keep := 4
+	if len(secret) <= 8 {
+		keep = 1
+	}
+	return secret[:keep] + strings.Repeat("*", min(len(secret)-keep, 12))
+}
@@ -0,0 +1,234 @@
+// Package sources merges memory shared from other repositories into the
+// agent paths of a project.
+//
+// In a single-file agent path such as CLAUDE.md, the text of each source is
+// appended in a block:
+//
+//	<!-- ai-docs:source org@1a2b3c4 -->
+//	...
+//	<!-- ai-docs:end-source -->
+//
+// Files added to a directory start with a header naming the source instead.
+// Both are rewritten on every pull and are not pushed to the doc branch.
+package sources
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/trknhr/ai-docs/markdown"
+)
+
+const (
+	// Marker starts a source block or header.
+	Marker = "ai-docs:source"
+	// EndMarker closes a source block.
+	EndMarker = "<!-- ai-docs:end-source -->"
+)
+
+var (
+	startRe  = regexp.MustCompile(`^\s*<!--\s*` + Marker + `\s+(\S+)\s*-->\s*$`)
+	headerRe = regexp.MustCompile(`^<!-- ` + Marker + ` (\S+) - .*-->$`)
+)
+
+// Block is the text a source contributes to a single-file agent path.
+type Block struct {
+	// Name is the name of the source, Commit the commit it was read at.
+	Name   string
+	Commit string
+	Data   []byte
+}
+
+func (b Block) label() string {
+	commit := b.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	return b.Name + "@" + commit
+}
+
+// Has reports whether data contains source blocks or a source header.
+func Has(data []byte) bool {
+	return bytes.Contains(data, []byte(Marker))
+}
+
+// Strip removes the source blocks from data.
+func Strip(data []byte) []byte {
+	if !Has(data) {
+		return data
+	}
+	lines := markdown.SplitLines(string(data))
+	var kept []string
+	next := 0
+	for _, span := range blockSpans(lines) {
+		kept = append(kept, lines[next:span[0]]...)
+		// Drop the blank line Merge puts before a block.
+		if n := len(kept); n > 0 && strings.TrimSpace(kept[n-1]) == "" {
+			kept = kept[:n-1]
+		}
+		next = span[1] + 1
+	}
+	kept = append(kept, lines[next:]...)
+	return []byte(strings.Join(kept, ""))
+}
+
+// blockSpans returns the indexes of the first and last line of every source
+// block in lines. A start line without an end marker after it is not a
+// block we wrote and is left alone.
+func blockSpans(lines []string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, line := range lines {
+		switch {
+		case start < 0 && startRe.MatchString(line):
+			start = i
+		case start >= 0 && strings.TrimSpace(line) == EndMarker:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	return spans
+}
+
+// Merge replaces the source blocks in data with blocks, in order. Sections
+// of a block whose heading already appears in data or in an earlier block
+// are left out, so that the project and earlier sources take precedence.
+// A leading "#" title of a block is dropped too.
+func Merge(data []byte, blocks []Block) []byte {
+	text := string(Strip(data))
+	seen := headings(text)
+
+	var b strings.Builder
+	b.WriteString(text)
+	for _, block := range blocks {
+		content := strings.TrimSpace(dropSections(string(block.Data), seen))
+		if content == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			if !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "<!-- %s %s -->\n%s\n%s\n", Marker, block.label(), content, EndMarker)
+	}
+	return []byte(b.String())
+}
+
+// Carry returns data followed by the source blocks of old, so that
+// rewriting a file does not drop what sources merged into it.
+func Carry(old, data []byte) []byte {
+	if !Has(old) {
+		return data
+	}
+	var blocks []Block
+	lines := markdown.SplitLines(string(old))
+	for _, span := range blockSpans(lines) {
+		m := startRe.FindStringSubmatch(lines[span[0]])
+		name, commit, _ := strings.Cut(m[1], "@")
+		blocks = append(blocks, Block{Name: name, Commit: commit, Data: []byte(strings.Join(lines[span[0]+1:span[1]], ""))})
+	}
+	if len(blocks) == 0 {
+		return data
+	}
+	return Merge(data, blocks)
+}
+
+// AddHeader marks data as a file copied from a source. In files with
+// frontmatter the header follows the frontmatter.
+func AddHeader(data []byte, name, commit string) []byte {
+	text := string(data)
+	header := fmt.Sprintf("<!-- %s %s - shared file, changes are overwritten on pull -->\n\n", Marker, Block{Name: name, Commit: commit}.label())
+
+	at := 0
+	if strings.HasPrefix(text, "---\n") {
+		if end := strings.Index(text[4:], "\n---\n"); end >= 0 {
+			at = 4 + end + len("\n---\n")
+			if strings.HasPrefix(text[at:], "\n") {
+				at++
+			}
+		}
+	}
+	return []byte(text[:at] + header + text[at:])
+}
+
+// Header returns the source named in the header added by AddHeader, and
+// whether data has one.
+func Header(data []byte) (string, bool) {
+	if !Has(data) {
+		return "", false
+	}
+	lines := markdown.SplitLines(string(data))
+	if len(lines) > 0 && strings.TrimRight(lines[0], "\r\n") == "---" {
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimRight(lines[i], "\r\n") == "---" {
+				lines = lines[i+1:]
+				break
+			}
+		}
+	}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if m := headerRe.FindStringSubmatch(line); m != nil {
+			name, _, _ := strings.Cut(m[1], "@")
+			return name, true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// dropSections removes the leading title of text and every section whose
+// heading is in seen, then adds the headings it keeps to seen.
+func dropSections(text string, seen map[string]bool) string {
+	var b strings.Builder
+	var kept []string
+	skip, skipLevel := false, 0
+	var fences markdown.Fences
+	for i, line := range markdown.SplitLines(text) {
+		fenced := fences.Next(line)
+		if level, heading, ok := markdown.Heading(line); ok && !fenced {
+			if i == 0 && level == 1 {
+				continue
+			}
+			if skip && level <= skipLevel {
+				skip = false
+			}
+			key := strings.ToLower(heading)
+			if !skip && seen[key] {
+				skip, skipLevel = true, level
+			}
+			if !skip {
+				kept = append(kept, key)
+			}
+		}
+		if !skip {
+			b.WriteString(line)
+		}
+	}
+	for _, key := range kept {
+		seen[key] = true
+	}
+	return b.String()
+}
+
+// headings returns the lowercased headings of markdown text.
+func headings(text string) map[string]bool {
+	seen := map[string]bool{}
+	var fences markdown.Fences
+	for _, line := range markdown.SplitLines(text) {
+		if fences.Next(line) {
+			continue
+		}
+		if _, heading, ok := markdown.Heading(line); ok {
+			seen[strings.ToLower(heading)] = true
+		}
+	}
+	return seen
+}
@@ -0,0 +1,192 @@
+package sources
+
+import (
+	"strings"
+	"testing"
+)
+
+func block(label, content string) string {
+	return "<!-- " + Marker + " " + label + " -->\n" + content + "\n" + EndMarker + "\n"
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		blocks []Block
+		want   string
+	}{
+		{
+			name:   "appends a block",
+			data:   "# Project\n\n## Build\nmake\n",
+			blocks: []Block{{Name: "org", Commit: "1a2b3c4d5e", Data: []byte("## Style\ngofmt\n")}},
+			want:   "# Project\n\n## Build\nmake\n\n" + block("org@1a2b3c4", "## Style\ngofmt"),
+		},
+		{
+			name:   "drops the title and sections the project has",
+			data:   "## Build\nmake\n",
+			blocks: []Block{{Name: "org", Commit: "abc", Data: []byte("# Org\n\n## build\nbazel\n\n## Style\ngofmt\n")}},
+			want:   "## Build\nmake\n\n" + block("org@abc", "## Style\ngofmt"),
+		},
+		{
+			name: "earlier sources win",
+			data: "",
+			blocks: []Block{
+				{Name: "a", Commit: "1", Data: []byte("## Style\nA\n")},
+				{Name: "b", Commit: "2", Data: []byte("## Style\nB\n\n## Tests\nB\n")},
+			},
+			want: block("a@1", "## Style\nA") + "\n" + block("b@2", "## Tests\nB"),
+		},
+		{
+			name:   "skips a block with nothing left",
+			data:   "## Style\nmine\n",
+			blocks: []Block{{Name: "org", Commit: "1", Data: []byte("## Style\ntheirs\n")}},
+			want:   "## Style\nmine\n",
+		},
+		{
+			name:   "ignores headings in code",
+			data:   "```\n## Style\n```\n",
+			blocks: []Block{{Name: "org", Commit: "1", Data: []byte("## Style\nx\n")}},
+			want:   "```\n## Style\n```\n\n" + block("org@1", "## Style\nx"),
+		},
+		{
+			name:   "replaces existing blocks",
+			data:   "mine\n\n" + block("org@old", "stale"),
+			blocks: []Block{{Name: "org", Commit: "new", Data: []byte("fresh\n")}},
+			want:   "mine\n\n" + block("org@new", "fresh"),
+		},
+		{
+			name:   "no trailing newline",
+			data:   "mine",
+			blocks: []Block{{Name: "org", Commit: "1", Data: []byte("x")}},
+			want:   "mine\n\n" + block("org@1", "x"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Merge([]byte(tt.data), tt.blocks)); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "no blocks",
+			data: "text\n",
+			want: "text\n",
+		},
+		{
+			name: "block at the end",
+			data: "mine\n\n" + block("org@1", "x"),
+			want: "mine\n",
+		},
+		{
+			name: "two blocks",
+			data: "mine\n\n" + block("a@1", "x") + "\n" + block("b@2", "y") + "tail\n",
+			want: "mine\ntail\n",
+		},
+		{
+			name: "start without an end is kept",
+			data: "mine\n<!-- " + Marker + " org@1 -->\nrest\n",
+			want: "mine\n<!-- " + Marker + " org@1 -->\nrest\n",
+		},
+		{
+			name: "only a block",
+			data: block("org@1", "x"),
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Strip([]byte(tt.data))); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripManyUnterminated(t *testing.T) {
+	data := strings.Repeat("<!-- "+Marker+" org@1 -->\n", 20000)
+	if got := string(Strip([]byte(data))); got != data {
+		t.Error("Strip changed unterminated start lines")
+	}
+}
+
+func TestCarry(t *testing.T) {
+	old := "old text\n\n" + block("org@1a2b3c4", "## Style\ngofmt")
+	tests := []struct {
+		name string
+		old  string
+		data string
+		want string
+	}{
+		{
+			name: "keeps blocks",
+			old:  old,
+			data: "new text\n",
+			want: "new text\n\n" + block("org@1a2b3c4", "## Style\ngofmt"),
+		},
+		{
+			name: "drops sections the new text has",
+			old:  old,
+			data: "## Style\nmine\n",
+			want: "## Style\nmine\n",
+		},
+		{
+			name: "no blocks",
+			old:  "old\n",
+			data: "new\n",
+			want: "new\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Carry([]byte(tt.old), []byte(tt.data))); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "plain", data: "# Rule\nbody\n"},
+		{name: "frontmatter", data: "---\ndescription: x\n---\n\n# Rule\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Header([]byte(tt.data)); ok {
+				t.Fatal("plain file reported a header")
+			}
+			marked := AddHeader([]byte(tt.data), "org", "1a2b3c4d5e6f")
+			name, ok := Header(marked)
+			if !ok || name != "org" {
+				t.Errorf("Header = %q, %v, want org, true:\n%s", name, ok, marked)
+			}
+			if !strings.Contains(string(marked), "org@1a2b3c4 ") {
+				t.Errorf("header does not name the short commit:\n%s", marked)
+			}
+			if strings.HasPrefix(tt.data, "---\n") && !strings.HasPrefix(string(marked), "---\n") {
+				t.Errorf("header was put before the frontmatter:\n%s", marked)
+			}
+		})
+	}
+
+	if _, ok := Header([]byte("text\n" + block("org@1", "x"))); ok {
+		t.Error("a block was taken for a header")
+	}
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ArchiveFile is a regular file stored in an archive.
+type ArchiveFile struct {
+	Name string
+	Mode os.FileMode
+	Data []byte
+}
+
+// ReadArchive returns the regular files in the zip, tar or tar.gz archive at
+// path. The format is detected from the content. Directories, symlinks and
+// other special entries are skipped.
+func ReadArchive(path string) ([]ArchiveFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")), bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return readZip(f, info.Size())
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer gz.Close()
+		return readTar(gz)
+	default:
+		return readTar(br)
+	}
+}
+
+func readTar(r io.Reader) ([]ArchiveFile, error) {
+	var files []ArchiveFile
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ArchiveFile{Name: hdr.Name, Mode: os.FileMode(hdr.Mode).Perm(), Data: data})
+	}
+}
+
+func readZip(r io.ReaderAt, size int64) ([]ArchiveFile, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+	var files []ArchiveFile
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from zip archive: %w", zf.Name, err)
+		}
+		files = append(files, ArchiveFile{Name: zf.Name, Mode: zf.Mode().Perm(), Data: data})
+	}
+	return files, nil
+}
+
+// WriteArchive writes files to path as a "zip", "tar" or "tar.gz" archive.
+// The archive is written under a temporary name and renamed into place.
+func WriteArchive(ctx context.Context, path, format string, files []ArchiveFile) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	modTime := time.Now()
+	switch format {
+	case "zip":
+		zw := zip.NewWriter(tmp)
+		for _, f := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			hdr := &zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: modTime}
+			hdr.SetMode(f.Mode)
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(f.Data); err != nil {
+				return err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	case "tar", "tar.gz":
+		var w io.Writer = tmp
+		var gz *gzip.Writer
+		if format == "tar.gz" {
+			gz = gzip.NewWriter(tmp)
+			w = gz
+		}
+		tw := tar.NewWriter(w)
+		for _, f := range files {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			hdr := &tar.Header{Name: f.Name, Mode: int64(f.Mode), Size: int64(len(f.Data)), ModTime: modTime, Typeflag: tar.TypeReg}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(f.Data); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		if gz != nil {
+			if err := gz.Close(); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
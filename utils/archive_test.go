@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	files := []ArchiveFile{
+		{Name: "CLAUDE.md", Mode: 0644, Data: []byte("# Claude\n")},
+		{Name: ".cursor/rules/run.sh", Mode: 0755, Data: []byte("#!/bin/sh\n")},
+		{Name: "empty.md", Mode: 0644, Data: []byte{}},
+	}
+	for _, format := range []string{"zip", "tar", "tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "bundle."+format)
+			if err := WriteArchive(context.Background(), path, format, files); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadArchive(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, files) {
+				t.Errorf("got %+v, want %+v", got, files)
+			}
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("temporary files left behind: %v", entries)
+			}
+		})
+	}
+}
+
+func TestWriteArchiveErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bundle.rar")
+	if err := WriteArchive(context.Background(), path, "rar", nil); err == nil {
+		t.Error("unsupported format was accepted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	files := []ArchiveFile{{Name: "a.md", Mode: 0644, Data: []byte("a")}}
+	if err := WriteArchive(ctx, filepath.Join(dir, "bundle.zip"), "zip", files); err == nil {
+		t.Error("cancelled write succeeded")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("failed writes left files behind: %v", entries)
+	}
+}
@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+)
+
+// bundleHeader is the part of a git bundle before the packfile.
+type bundleHeader struct {
+	prerequisites []plumbing.Hash
+	refs          map[string]plumbing.Hash
+}
+
+// readBundleHeader parses a v2 or v3 bundle header from r, leaving r at the
+// start of the packfile.
+func readBundleHeader(r *bufio.Reader) (*bundleHeader, error) {
+	sig, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	switch strings.TrimSuffix(sig, "\n") {
+	case "# v2 git bundle", "# v3 git bundle":
+	default:
+		return nil, errors.New("not a v2 or v3 git bundle")
+	}
+
+	h := &bundleHeader{refs: map[string]plumbing.Hash{}}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("truncated bundle header: %w", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return h, nil
+		case strings.HasPrefix(line, "@"):
+			if line != "@object-format=sha1" && !strings.HasPrefix(line, "@filter=") {
+				return nil, fmt.Errorf("unsupported bundle capability %q", line)
+			}
+		case strings.HasPrefix(line, "-"):
+			sha, _, _ := strings.Cut(line[1:], " ")
+			h.prerequisites = append(h.prerequisites, plumbing.NewHash(sha))
+		default:
+			sha, ref, ok := strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid bundle header line %q", line)
+			}
+			h.refs[ref] = plumbing.NewHash(sha)
+		}
+	}
+}
+
+func (g *GoGit) CreateBundle(ctx context.Context, file, branch string, exclude []string) (err error) {
+	args := append([]string{"bundle", "create", file, "refs/heads/" + branch}, exclude...)
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	tip, err := g.resolve(repo, "refs/heads/"+branch)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	var basis []plumbing.Hash
+	for _, rev := range exclude {
+		h, err := g.resolve(repo, rev)
+		if err != nil {
+			return gogitError(err, args...)
+		}
+		basis = append(basis, h)
+	}
+
+	hashes, err := revlist.Objects(repo.Storer, []plumbing.Hash{tip}, basis)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	if len(hashes) == 0 {
+		return &GitError{Args: args, ExitCode: -1, Kind: ErrUpToDate, Err: errors.New("refusing to create empty bundle")}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintln(w, "# v2 git bundle")
+	for _, h := range basis {
+		fmt.Fprintf(w, "-%s\n", h)
+	}
+	fmt.Fprintf(w, "%s refs/heads/%s\n\n", tip, branch)
+	if _, err := packfile.NewEncoder(w, repo.Storer, false).Encode(hashes, 10); err != nil {
+		return gogitError(err, args...)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+func (g *GoGit) BundleHeads(ctx context.Context, file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h, err := readBundleHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, gogitError(err, "bundle", "list-heads", file)
+	}
+	heads := make(map[string]string, len(h.refs))
+	for ref, hash := range h.refs {
+		heads[ref] = hash.String()
+	}
+	return heads, nil
+}
+
+func (g *GoGit) FetchBundle(ctx context.Context, file, ref, dst string) error {
+	args := []string{"fetch", file, "+" + ref + ":" + dst}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	h, err := readBundleHeader(r)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	var missing []string
+	for _, p := range h.prerequisites {
+		if repo.Storer.HasEncodedObject(p) != nil {
+			missing = append(missing, p.String())
+		}
+	}
+	if len(missing) > 0 {
+		return gogitError(fmt.Errorf("repository lacks these prerequisite commits: %s", strings.Join(missing, " ")), args...)
+	}
+	hash, ok := h.refs[ref]
+	if !ok {
+		return gogitError(fmt.Errorf("couldn't find remote ref %s", ref), args...)
+	}
+
+	if err := packfile.UpdateObjectStorage(repo.Storer, ctxReader{ctx, r}); err != nil && !errors.Is(err, io.EOF) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return gogitError(err, args...)
+	}
+	return gogitError(repo.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(dst), hash)), args...)
+}
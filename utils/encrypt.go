@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// ErrNoIdentity is returned when an encrypted file cannot be decrypted
+// because no matching identity or passphrase is available.
+var ErrNoIdentity = errors.New("no matching identity or passphrase")
+
+// Encryptor encrypts files for the doc branch with age and decrypts them
+// again. Encrypted files are ASCII armored so that they stay readable text
+// in git.
+type Encryptor struct {
+	recipients []age.Recipient
+	identities []age.Identity
+	rekey      bool
+
+	// known maps the SHA-256 of ciphertexts this machine has seen to the
+	// SHA-256 of their plaintext, so that Encrypt can tell an unchanged file
+	// without decrypting it. It is saved to knownPath if that is set.
+	mu        sync.Mutex
+	known     map[string]string
+	knownPath string
+}
+
+// NewEncryptor returns an Encryptor that encrypts to recipients and decrypts
+// with identities. Either may be empty if only one direction is needed.
+func NewEncryptor(recipients []age.Recipient, identities []age.Identity) *Encryptor {
+	return &Encryptor{recipients: recipients, identities: identities, known: map[string]string{}}
+}
+
+// NewPassphraseEncryptor returns an Encryptor that uses passphrase in both
+// directions.
+func NewPassphraseEncryptor(passphrase string) (*Encryptor, error) {
+	r, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	i, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptor([]age.Recipient{r}, []age.Identity{i}), nil
+}
+
+// ReadIdentities parses the age identity file at path.
+func ReadIdentities(path string) ([]age.Identity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity file %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// Rekey makes Encrypt encrypt every file anew, even if its plaintext is
+// unchanged. Use it when the recipients have changed, since existing
+// ciphertext is only readable by the recipients it was made for.
+func (e *Encryptor) Rekey() {
+	e.rekey = true
+}
+
+// CanDecrypt reports whether e has an identity or passphrase to decrypt with.
+func (e *Encryptor) CanDecrypt() bool {
+	return len(e.identities) > 0
+}
+
+// IsEncrypted reports whether data is an age file, armored or binary.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(armor.Header)) || bytes.HasPrefix(data, []byte("age-encryption.org/"))
+}
+
+// Encrypt is a Transform that encrypts plain for dst. If dst already holds
+// an encryption of the same plaintext it is returned unchanged, because age
+// output differs on every run and would otherwise show every file as
+// modified. Rekey turns this off.
+func (e *Encryptor) Encrypt(dst string, plain []byte) ([]byte, error) {
+	existing, err := os.ReadFile(dst)
+	if err == nil && !e.rekey && IsEncrypted(existing) {
+		// Look the ciphertext up before decrypting it, which costs a full
+		// scrypt run per file with a passphrase.
+		plainHash, ok := e.lookup(existing)
+		if !ok && len(e.identities) > 0 {
+			if old, err := e.decrypt(existing); err == nil {
+				plainHash, ok = e.remember(existing, old), true
+			}
+		}
+		if ok && plainHash == hashHex(plain) {
+			return existing, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, e.recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", dst, err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", dst, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", dst, err)
+	}
+	if err := aw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt %s: %w", dst, err)
+	}
+	e.forget(existing)
+	e.remember(buf.Bytes(), plain)
+	return buf.Bytes(), nil
+}
+
+// Decrypt is a Transform that decrypts data for dst. Data that is not
+// encrypted, such as files committed before encryption was enabled, is
+// passed through.
+func (e *Encryptor) Decrypt(dst string, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	plain, err := e.decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", dst, err)
+	}
+	e.remember(data, plain)
+	return plain, nil
+}
+
+func (e *Encryptor) decrypt(data []byte) ([]byte, error) {
+	if len(e.identities) == 0 {
+		return nil, ErrNoIdentity
+	}
+
+	var src io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte(armor.Header)) {
+		src = armor.NewReader(src)
+	}
+	r, err := age.Decrypt(src, e.identities...)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrNoIdentity
+		}
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// UseKnownFile loads the plaintext hashes of known ciphertexts from path, a
+// file local to this machine, and saves new ones there. A missing file is
+// created when the first one is recorded.
+func (e *Encryptor) UseKnownFile(path string) error {
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		known := map[string]string{}
+		if err := json.Unmarshal(data, &known); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for cipher, plain := range known {
+			e.known[cipher] = plain
+		}
+	}
+	e.knownPath = path
+	return nil
+}
+
+func (e *Encryptor) lookup(cipher []byte) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	plainHash, ok := e.known[hashHex(cipher)]
+	return plainHash, ok
+}
+
+// remember records that cipher decrypts to plain and returns the hash of plain.
+func (e *Encryptor) remember(cipher, plain []byte) string {
+	plainHash := hashHex(plain)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.known[hashHex(cipher)] != plainHash {
+		e.known[hashHex(cipher)] = plainHash
+		e.saveKnown()
+	}
+	return plainHash
+}
+
+// forget drops a ciphertext that has been replaced.
+func (e *Encryptor) forget(cipher []byte) {
+	if cipher == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.known[hashHex(cipher)]; ok {
+		delete(e.known, hashHex(cipher))
+		e.saveKnown()
+	}
+}
+
+// saveKnown writes the known hashes to knownPath. Failing to save only costs
+// a decryption later, so errors are ignored.
+func (e *Encryptor) saveKnown() {
+	if e.knownPath == "" {
+		return
+	}
+	data, err := json.Marshal(e.known)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(e.knownPath), 0755); err != nil {
+		return
+	}
+	tmp := e.knownPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err == nil {
+		os.Rename(tmp, e.knownPath)
+	}
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
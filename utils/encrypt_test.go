@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func newIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	alice, bob, eve := newIdentity(t), newIdentity(t), newIdentity(t)
+	passphrase, err := NewPassphraseEncryptor("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep scrypt cheap; the default work factor takes about a second.
+	passphrase.recipients[0].(*age.ScryptRecipient).SetWorkFactor(10)
+
+	tests := []struct {
+		name    string
+		enc     *Encryptor
+		dec     *Encryptor
+		wantErr error
+	}{
+		{
+			name: "identity",
+			enc:  NewEncryptor([]age.Recipient{alice.Recipient()}, nil),
+			dec:  NewEncryptor(nil, []age.Identity{alice}),
+		},
+		{
+			name: "second recipient",
+			enc:  NewEncryptor([]age.Recipient{alice.Recipient(), bob.Recipient()}, nil),
+			dec:  NewEncryptor(nil, []age.Identity{bob}),
+		},
+		{
+			name: "passphrase",
+			enc:  passphrase,
+			dec:  passphrase,
+		},
+		{
+			name:    "not a recipient",
+			enc:     NewEncryptor([]age.Recipient{alice.Recipient()}, nil),
+			dec:     NewEncryptor(nil, []age.Identity{eve}),
+			wantErr: ErrNoIdentity,
+		},
+		{
+			name:    "no identity",
+			enc:     NewEncryptor([]age.Recipient{alice.Recipient()}, nil),
+			dec:     NewEncryptor(nil, nil),
+			wantErr: ErrNoIdentity,
+		},
+	}
+
+	plain := []byte("# Memory\n\nsecret notes\n")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "CLAUDE.md")
+			cipher, err := tt.enc.Encrypt(dst, plain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsEncrypted(cipher) || bytes.Contains(cipher, []byte("secret")) {
+				t.Fatalf("not encrypted:\n%s", cipher)
+			}
+			got, err := tt.dec.Decrypt(dst, cipher)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("got %q, want %q", got, plain)
+			}
+		})
+	}
+}
+
+func TestDecryptPassesPlaintext(t *testing.T) {
+	got, err := NewEncryptor(nil, nil).Decrypt("a.md", []byte("plain\n"))
+	if err != nil || string(got) != "plain\n" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestEncryptReuse(t *testing.T) {
+	alice, bob := newIdentity(t), newIdentity(t)
+	dst := filepath.Join(t.TempDir(), "CLAUDE.md")
+	plain := []byte("notes\n")
+
+	enc := NewEncryptor([]age.Recipient{alice.Recipient()}, []age.Identity{alice})
+	first, err := enc.Encrypt(dst, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dst, string(first))
+
+	again, err := enc.Encrypt(dst, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, first) {
+		t.Error("unchanged plaintext was encrypted anew")
+	}
+	changed, err := enc.Encrypt(dst, []byte("more notes\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(changed, first) {
+		t.Error("changed plaintext kept the old ciphertext")
+	}
+
+	// After adding bob, the file must be encrypted anew so bob can read it.
+	rekeyed := NewEncryptor([]age.Recipient{alice.Recipient(), bob.Recipient()}, []age.Identity{alice})
+	rekeyed.Rekey()
+	cipher, err := rekeyed.Encrypt(dst, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(cipher, first) {
+		t.Fatal("Rekey kept the old ciphertext")
+	}
+	got, err := NewEncryptor(nil, []age.Identity{bob}).Decrypt(dst, cipher)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("new recipient got %q, %v", got, err)
+	}
+}
+
+func TestEncryptKnownHashes(t *testing.T) {
+	dir := t.TempDir()
+	known := filepath.Join(dir, "known.json")
+	dst := filepath.Join(dir, "CLAUDE.md")
+	plain := []byte("notes\n")
+
+	enc, err := NewPassphraseEncryptor("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.recipients[0].(*age.ScryptRecipient).SetWorkFactor(10)
+	if err := enc.UseKnownFile(known); err != nil {
+		t.Fatal(err)
+	}
+	first, err := enc.Encrypt(dst, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dst, string(first))
+
+	// A later run knows the ciphertext without decrypting it: the wrong
+	// passphrase would fail to.
+	later, err := NewPassphraseEncryptor("wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	later.recipients[0].(*age.ScryptRecipient).SetWorkFactor(10)
+	if err := later.UseKnownFile(known); err != nil {
+		t.Fatal(err)
+	}
+	again, err := later.Encrypt(dst, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, first) {
+		t.Error("unchanged plaintext was encrypted anew")
+	}
+	changed, err := later.Encrypt(dst, []byte("more notes\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(changed, first) {
+		t.Error("changed plaintext kept the old ciphertext")
+	}
+}
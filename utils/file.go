@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -108,11 +110,75 @@ func EnsureSymlinkIfExists(from, to string) error {
 	return nil
 }
 
-func CopyDir(src, dst string) error {
+// CopyPath copies the file or directory src to dst, merging into an existing
+// directory. Files are written under a temporary name and renamed into place,
+// so an interrupted copy never leaves a truncated file. If the copy fails or
+// ctx is cancelled, the files and directories it created are removed again.
+func CopyPath(ctx context.Context, src, dst string) error {
+	return CopyPathWith(ctx, src, dst, nil)
+}
+
+// Transform rewrites the contents of a file on its way to dst. dst may not
+// exist yet. Returning ErrSkipFile leaves dst alone.
+type Transform func(dst string, data []byte) ([]byte, error)
+
+// ErrSkipFile is returned by a Transform to skip copying a file.
+var ErrSkipFile = errors.New("skip file")
+
+// ChainTransforms returns a Transform that applies transforms in order,
+// skipping nil ones. It returns nil if all of them are nil.
+func ChainTransforms(transforms ...Transform) Transform {
+	var chain []Transform
+	for _, t := range transforms {
+		if t != nil {
+			chain = append(chain, t)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return func(dst string, data []byte) ([]byte, error) {
+		var err error
+		for _, t := range chain {
+			if data, err = t(dst, data); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+}
+
+// CopyPathWith is CopyPath with every file passed through transform, which
+// may be nil.
+func CopyPathWith(ctx context.Context, src, dst string, transform Transform) (err error) {
+	var created []string
+	defer func() {
+		if err != nil {
+			for i := len(created) - 1; i >= 0; i-- {
+				os.Remove(created[i])
+			}
+		}
+	}()
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		if err := mkdirAll(filepath.Dir(dst), 0755, &created); err != nil {
+			return err
+		}
+		return copyFile(ctx, src, dst, transform, &created)
+	}
+
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		relPath, err := filepath.Rel(src, path)
 		if err != nil {
@@ -122,26 +188,32 @@ func CopyDir(src, dst string) error {
 		dstPath := filepath.Join(dst, relPath)
 
 		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
+			return mkdirAll(dstPath, info.Mode(), &created)
 		}
 
-		return copyFile(path, dstPath)
+		return copyFile(ctx, path, dstPath, transform, &created)
 	})
 }
 
-func CopyPath(src, dst string) error {
-	info, err := os.Stat(src)
-	if err != nil {
+// mkdirAll is os.MkdirAll that records the directories it creates.
+func mkdirAll(dir string, perm os.FileMode, created *[]string) error {
+	if info, err := os.Stat(dir); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s exists and is not a directory", dir)
+		}
+		return nil
+	}
+	if err := mkdirAll(filepath.Dir(dir), perm, created); err != nil {
 		return err
 	}
-
-	if info.IsDir() {
-		return CopyDir(src, dst)
+	if err := os.Mkdir(dir, perm); err != nil {
+		return err
 	}
-	return copyFile(src, dst)
+	*created = append(*created, dir)
+	return nil
 }
 
-func copyFile(src, dst string) error {
+func copyFile(ctx context.Context, src, dst string, transform Transform, created *[]string) error {
 	source, err := os.Open(src)
 	if err != nil {
 		return err
@@ -152,18 +224,62 @@ func copyFile(src, dst string) error {
 		}
 	}()
 
-	destination, err := os.Create(dst)
+	info, err := source.Stat()
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := destination.Close(); err != nil {
-			log.Printf("failed to close file: %v", err)
+	existed := PathExists(dst)
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if transform == nil {
+		_, err = io.Copy(tmp, ctxReader{ctx, source})
+	} else {
+		var data []byte
+		data, err = io.ReadAll(ctxReader{ctx, source})
+		if err == nil {
+			data, err = transform(dst, data)
 		}
-	}()
+		if err == nil {
+			_, err = tmp.Write(data)
+		}
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		if errors.Is(err, ErrSkipFile) {
+			return nil
+		}
+		return err
+	}
 
-	_, err = io.Copy(destination, source)
-	return err
+	if !existed {
+		*created = append(*created, dst)
+	}
+	return nil
+}
+
+// ctxReader stops reading once ctx is cancelled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
 
 func PathExists(path string) bool {
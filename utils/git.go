@@ -1,83 +1,229 @@
 package utils
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 )
 
-func RunGit(dir string, args ...string) error {
-	cmd := exec.Command("git", args...)
-	if dir != "" {
-		cmd.Dir = dir
-	}
+// Git is the set of git operations ai-docs performs. Methods that take a dir
+// run in that worktree; an empty dir means the main working tree. Cancelling
+// ctx aborts the operation in flight.
+type Git interface {
+	// Branches
+	BranchExists(ctx context.Context, branch string) bool
+	CurrentBranch(ctx context.Context) (string, error)
+	CreateBranch(ctx context.Context, branch, startPoint string) error
+	CreateTrackingBranch(ctx context.Context, remote, branch string) error
+	CreateOrphanBranch(ctx context.Context, branch, dir string, paths []string, message string) (string, error)
+	DeleteBranch(ctx context.Context, branch string) error
+	ResolveRef(ctx context.Context, ref string) (string, error)
+	Upstream(ctx context.Context, branch string) (string, error)
+	// Ahead returns the number of commits reachable from rev but not from
+	// base.
+	Ahead(ctx context.Context, rev, base string) (int, error)
+	SetUpstream(ctx context.Context, remote, branch string) error
+	Switch(ctx context.Context, branch string) error
+
+	// Worktrees
+	AddWorktree(ctx context.Context, dir, branch string) error
+	RemoveWorktree(ctx context.Context, dir string) error
+	ListWorktrees(ctx context.Context) ([]Worktree, error)
+	PruneWorktrees(ctx context.Context) error
+
+	// Commits and status
+	StageAll(ctx context.Context, dir string) error
+	Commit(ctx context.Context, dir, message string) error
+	HasChanges(ctx context.Context, dir string) (bool, error)
+	TrackedFiles(ctx context.Context, paths ...string) ([]string, error)
+	Untrack(ctx context.Context, paths ...string) error
+
+	// Remotes
+	RemoteBranchExists(ctx context.Context, remote, branch string) (bool, error)
+	Fetch(ctx context.Context, remote, branch string) error
+	// Pull fetches the upstream of the branch checked out in dir and
+	// rebases the branch onto it, like Rebase.
+	Pull(ctx context.Context, dir string) error
+	// Rebase replays the commits of the branch checked out in dir that are
+	// not in onto on top of onto. On conflicts it leaves the branch as it
+	// was and returns an ErrConflict error.
+	Rebase(ctx context.Context, dir, onto string) error
+	Push(ctx context.Context, dir, remote, branch string) error
+	DeleteRemoteBranch(ctx context.Context, remote, branch string) error
+
+	// Bundles
+	// CreateBundle writes branch to the git bundle file, leaving out the
+	// commits reachable from exclude, which become the bundle's
+	// prerequisites. It fails with ErrUpToDate if nothing is left.
+	CreateBundle(ctx context.Context, file, branch string, exclude []string) error
+	// BundleHeads returns the refs in a bundle and the commits they point to.
+	BundleHeads(ctx context.Context, file string) (map[string]string, error)
+	// FetchBundle stores ref from a bundle as dst. It fails with ErrNotFound
+	// if the repository lacks one of the bundle's prerequisites.
+	FetchBundle(ctx context.Context, file, ref, dst string) error
+
+	// Other repositories
+	// Clone makes a shallow checkout of ref (a branch, tag or commit; empty
+	// means the default branch) of the repository at url in the new
+	// directory dir and returns the commit it checked out.
+	Clone(ctx context.Context, url, ref, dir string) (string, error)
+
+	// Repository
+	GitPath(ctx context.Context, name string) (string, error)
+	// SetConfig sets key (section.name or section.subsection.name) in the
+	// repository config shared by all worktrees.
+	SetConfig(ctx context.Context, key, value string) error
+	Archive(ctx context.Context, rev, format, output string) error
+}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// Worktree is an entry of `git worktree list`.
+type Worktree struct {
+	Path     string
+	Head     string
+	Branch   string
+	Prunable bool
+}
 
-	err := cmd.Run()
-	if err != nil {
-		return fmt.Errorf("git %s failed: %v\nstderr: %s", strings.Join(args, " "), err, stderr.String())
-	}
+var gitClient Git
 
-	return nil
+// GitClient returns the Git implementation used by the commands. Unless one
+// was set with SetGitClient, it is chosen by DetectGitClient.
+func GitClient() Git {
+	if gitClient == nil {
+		gitClient = DetectGitClient()
+	}
+	return gitClient
 }
 
-func RunGitWithOutput(dir string, args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	if dir != "" {
-		cmd.Dir = dir
-	}
+// minGitVersion is the oldest git binary ExecGit works with; `git switch`
+// needs 2.23.
+var minGitVersion = [2]int{2, 23}
 
-	output, err := cmd.CombinedOutput()
+// DetectGitClient returns ExecGit when a recent enough git binary is on PATH
+// and GoGit otherwise.
+func DetectGitClient() Git {
+	output, err := exec.Command("git", "version").Output()
 	if err != nil {
-		return "", fmt.Errorf("git %s failed: %v\noutput: %s", strings.Join(args, " "), err, string(output))
+		return &GoGit{}
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	// e.g. "git version 2.39.2" or "git version 2.39.2.windows.1"
+	fields := strings.Fields(string(output))
+	if len(fields) < 3 {
+		return &GoGit{}
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return &GoGit{}
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return &GoGit{}
+	}
+	if major < minGitVersion[0] || (major == minGitVersion[0] && minor < minGitVersion[1]) {
+		return &GoGit{}
+	}
+	return &ExecGit{}
 }
 
-func BranchExists(branch string) bool {
-	_, err := RunGitWithOutput("", "rev-parse", "--verify", branch)
-	return err == nil
+// SetGitClient replaces the Git implementation, e.g. with a fake in tests.
+func SetGitClient(g Git) {
+	gitClient = g
 }
 
-func GetCurrentBranch() (string, error) {
-	return RunGitWithOutput("", "branch", "--show-current")
+// PushOptions controls PushWithRetry.
+type PushOptions struct {
+	// Retries is the number of attempts made after the first one.
+	Retries int
+	// Timeout bounds the total time spent retrying; zero means no limit.
+	Timeout time.Duration
+	// Rebase integrates the remote commits into the worktree at dir when the
+	// push is rejected as non-fast-forward, instead of failing.
+	Rebase bool
 }
 
-func PushWithRetry(dir, branch string, maxRetries int) error {
-	var lastErr error
+const (
+	pushBackoffBase = 500 * time.Millisecond
+	pushBackoffMax  = 30 * time.Second
+)
 
-	for i := 0; i < maxRetries; i++ {
-		if i > 0 {
-			backoff := time.Duration(i) * time.Second
-			time.Sleep(backoff)
-		}
+// PushWithRetry pushes branch from dir to origin. Network errors are retried
+// with exponential backoff and jitter. A non-fast-forward rejection means
+// another machine pushed first: with opts.Rebase the remote branch is fetched,
+// the local commits are rebased onto it and the push is retried. Other
+// rejections and authentication failures are returned immediately.
+func PushWithRetry(ctx context.Context, g Git, dir, branch string, opts PushOptions) error {
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
 
-		err := RunGit(dir, "push", "origin", branch)
+	for attempt := 0; ; attempt++ {
+		err := g.Push(ctx, dir, "origin", branch)
 		if err == nil {
 			return nil
 		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		rebased := false
+		switch {
+		case errors.Is(err, ErrNonFastForward) && opts.Rebase:
+			if ferr := g.Fetch(ctx, "origin", branch); ferr != nil {
+				if !errors.Is(ferr, ErrNetwork) {
+					return fmt.Errorf("failed to fetch origin/%s: %w", branch, ferr)
+				}
+				err = ferr
+				break
+			}
+			if rerr := g.Rebase(ctx, dir, "origin/"+branch); rerr != nil {
+				return fmt.Errorf("failed to rebase onto origin/%s: %w", branch, rerr)
+			}
+			rebased = true
+		case errors.Is(err, ErrNetwork), errors.Is(err, ErrUnknown):
+		default:
+			return err
+		}
 
-		lastErr = err
+		if attempt >= opts.Retries {
+			return fmt.Errorf("push failed after %d attempt(s): %w", attempt+1, err)
+		}
+
+		// After a rebase the next push can succeed right away.
+		var wait time.Duration
+		if !rebased {
+			wait = pushBackoff(attempt)
+		}
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("push timed out after %s: %w", opts.Timeout, err)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+}
 
-	return fmt.Errorf("push failed after %d retries: %w", maxRetries, lastErr)
+// pushBackoff returns the wait before retry attempt+1: exponential in attempt,
+// capped, with jitter so that machines pushing together spread out.
+func pushBackoff(attempt int) time.Duration {
+	d := pushBackoffMax
+	if attempt < 16 {
+		d = min(pushBackoffBase<<attempt, pushBackoffMax)
+	}
+	return d/2 + rand.N(d/2+1)
 }
 
 func IsGitRepo() bool {
 	_, err := os.Stat(".git")
 	return err == nil
 }
-
-func HasUncommittedChanges(dir string) bool {
-	output, err := RunGitWithOutput(dir, "status", "--porcelain")
-	if err != nil {
-		return false
-	}
-	return output != ""
-}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error kinds reported by GitError. Use errors.Is to test for them.
+var (
+	ErrUnknown        = errors.New("git command failed")
+	ErrNotRepository  = errors.New("not a git repository")
+	ErrNotFound       = errors.New("not found")
+	ErrAlreadyExists  = errors.New("already exists")
+	ErrNoUpstream     = errors.New("no upstream branch")
+	ErrNonFastForward = errors.New("rejected as non-fast-forward")
+	ErrRemoteRejected = errors.New("rejected by remote")
+	ErrAuthFailed     = errors.New("authentication failed")
+	ErrNetwork        = errors.New("network error")
+	ErrConflict       = errors.New("conflict")
+	ErrUpToDate       = errors.New("already up to date")
+)
+
+// GitError is returned when a git command exits with an error.
+type GitError struct {
+	Args     []string
+	Stderr   string
+	ExitCode int
+	// Kind is one of the Err* sentinel errors above.
+	Kind error
+	Err  error
+}
+
+func (e *GitError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("git %s failed: %v", strings.Join(e.Args, " "), e.Err)
+	}
+	return fmt.Sprintf("git %s failed: %v\nstderr: %s", strings.Join(e.Args, " "), e.Err, e.Stderr)
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+func (e *GitError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// errorPatterns maps stderr fragments to error kinds. Order matters: the
+// first match wins, so more specific patterns come first.
+var errorPatterns = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrNotRepository, []string{"not a git repository"}},
+	{ErrNoUpstream, []string{
+		"no tracking information",
+		"has no upstream branch",
+		"no upstream configured",
+		"does not point to a branch",
+	}},
+	{ErrNonFastForward, []string{"non-fast-forward", "fetch first", "tip of your current branch is behind"}},
+	{ErrRemoteRejected, []string{"[remote rejected]", "[rejected]", "hook declined", "protected branch"}},
+	{ErrAuthFailed, []string{
+		"authentication failed",
+		"authentication required",
+		"authorization failed",
+		"permission denied",
+		"could not read username",
+		"could not read password",
+		"terminal prompts disabled",
+		"access denied",
+	}},
+	{ErrNetwork, []string{
+		"could not resolve host",
+		"no such host",
+		"i/o timeout",
+		"connection refused",
+		"connection timed out",
+		"operation timed out",
+		"network is unreachable",
+		"unable to access",
+		"connection reset",
+		"the remote end hung up unexpectedly",
+		"early eof",
+	}},
+	{ErrUpToDate, []string{"refusing to create empty bundle"}},
+	{ErrConflict, []string{
+		"conflict",
+		"could not apply",
+		"unmerged files",
+		"not possible to fast-forward",
+		"cannot rebase",
+		"cannot pull with rebase",
+		"would be overwritten",
+	}},
+	{ErrNotFound, []string{
+		"couldn't find remote ref",
+		"lacks these prerequisite commits",
+		"does not appear to be a git repository",
+		"not a valid object name",
+		"unknown revision",
+		"not a valid ref",
+		"needed a single revision",
+		"did not match any",
+		"not found",
+		"is not a working tree",
+	}},
+	{ErrAlreadyExists, []string{"already exists", "cannot lock ref"}},
+}
+
+func classifyGitError(stderr string) error {
+	lower := strings.ToLower(stderr)
+	for _, entry := range errorPatterns {
+		for _, p := range entry.patterns {
+			if strings.Contains(lower, p) {
+				return entry.kind
+			}
+		}
+	}
+	return ErrUnknown
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyGitError(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   error
+	}{
+		{"fatal: not a git repository (or any of the parent directories): .git", ErrNotRepository},
+		{"There is no tracking information for the current branch.", ErrNoUpstream},
+		{"fatal: The current branch main has no upstream branch.", ErrNoUpstream},
+		{" ! [rejected]        main -> main (fetch first)", ErrNonFastForward},
+		{" ! [rejected]        main -> main (non-fast-forward)", ErrNonFastForward},
+		{" ! [remote rejected] main -> main (pre-receive hook declined)", ErrRemoteRejected},
+		{"remote: Permission denied to alice.", ErrAuthFailed},
+		{"fatal: could not read Username for 'https://github.com': terminal prompts disabled", ErrAuthFailed},
+		{"fatal: unable to access 'https://x/': Could not resolve host: x", ErrNetwork},
+		{"fatal: unable to access 'http://127.0.0.1:1/x.git/': Failed to connect: Connection refused", ErrNetwork},
+		{"fatal: refusing to create empty bundle.", ErrUpToDate},
+		{"CONFLICT (content): Merge conflict in a.md\nerror: could not apply 1a2b3c4", ErrConflict},
+		{"error: cannot rebase: You have unstaged changes.\nerror: Please commit or stash them.", ErrConflict},
+		{"error: cannot rebase: Your index contains uncommitted changes.", ErrConflict},
+		{"error: The following untracked working tree files would be overwritten by checkout:\n\tb.md", ErrConflict},
+		{"fatal: couldn't find remote ref @doc/alice", ErrNotFound},
+		{"fatal: '/nonexistent' does not appear to be a git repository", ErrNotFound},
+		{"fatal: ambiguous argument 'x': unknown revision or path not in the working tree.", ErrNotFound},
+		{"fatal: a branch named 'main' already exists", ErrAlreadyExists},
+		{"error: cannot lock ref 'refs/heads/x'", ErrAlreadyExists},
+		{"something unexpected", ErrUnknown},
+		{"", ErrUnknown},
+	}
+	for _, tt := range tests {
+		if got := classifyGitError(tt.stderr); got != tt.want {
+			t.Errorf("classifyGitError(%q) = %v, want %v", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestGitErrorIs(t *testing.T) {
+	err := error(&GitError{Args: []string{"push"}, Kind: ErrNonFastForward, Err: errors.New("exit status 1")})
+	if !errors.Is(err, ErrNonFastForward) {
+		t.Error("errors.Is does not match the kind")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Error("errors.Is matched another kind")
+	}
+}
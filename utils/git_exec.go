@@ -0,0 +1,314 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecGit implements Git by running the git binary.
+type ExecGit struct{}
+
+// gitWaitDelay is how long a cancelled git process may take to exit.
+const gitWaitDelay = 5 * time.Second
+
+// run executes git in dir with optional stdin and returns its trimmed stdout.
+func (g *ExecGit) run(ctx context.Context, dir string, stdin io.Reader, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// On cancellation, interrupt git first so that it can remove its lock
+	// files; it is killed if it has not exited after gitWaitDelay.
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = gitWaitDelay
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	cmd.Stdin = stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		gitErr := &GitError{
+			Args:     args,
+			Stderr:   stderr.String(),
+			ExitCode: -1,
+			Err:      err,
+		}
+		if ctx.Err() != nil {
+			gitErr.Err = ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			gitErr.ExitCode = exitErr.ExitCode()
+		}
+		gitErr.Kind = classifyGitError(stderr.String())
+		return "", gitErr
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (g *ExecGit) git(ctx context.Context, dir string, args ...string) error {
+	_, err := g.run(ctx, dir, nil, args...)
+	return err
+}
+
+func (g *ExecGit) output(ctx context.Context, dir string, args ...string) (string, error) {
+	return g.run(ctx, dir, nil, args...)
+}
+
+func (g *ExecGit) BranchExists(ctx context.Context, branch string) bool {
+	return g.git(ctx, "", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch) == nil
+}
+
+func (g *ExecGit) CurrentBranch(ctx context.Context) (string, error) {
+	return g.output(ctx, "", "branch", "--show-current")
+}
+
+func (g *ExecGit) CreateBranch(ctx context.Context, branch, startPoint string) error {
+	return g.git(ctx, "", "branch", branch, startPoint)
+}
+
+// CreateTrackingBranch fetches branch from remote and creates a local branch tracking it.
+func (g *ExecGit) CreateTrackingBranch(ctx context.Context, remote, branch string) error {
+	if err := g.Fetch(ctx, remote, branch); err != nil {
+		return err
+	}
+	return g.git(ctx, "", "branch", "--track", branch, remote+"/"+branch)
+}
+
+func (g *ExecGit) DeleteBranch(ctx context.Context, branch string) error {
+	return g.git(ctx, "", "branch", "-D", branch)
+}
+
+func (g *ExecGit) ResolveRef(ctx context.Context, ref string) (string, error) {
+	return g.output(ctx, "", "rev-parse", "--verify", ref)
+}
+
+// Upstream returns the upstream of branch, e.g. "origin/main".
+func (g *ExecGit) Upstream(ctx context.Context, branch string) (string, error) {
+	return g.output(ctx, "", "rev-parse", "--abbrev-ref", branch+"@{upstream}")
+}
+
+func (g *ExecGit) Ahead(ctx context.Context, rev, base string) (int, error) {
+	output, err := g.output(ctx, "", "rev-list", "--count", base+".."+rev)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(output)
+}
+
+func (g *ExecGit) SetUpstream(ctx context.Context, remote, branch string) error {
+	return g.git(ctx, "", "branch", "--set-upstream-to="+remote+"/"+branch, branch)
+}
+
+func (g *ExecGit) Switch(ctx context.Context, branch string) error {
+	return g.git(ctx, "", "switch", branch)
+}
+
+func (g *ExecGit) AddWorktree(ctx context.Context, dir, branch string) error {
+	return g.git(ctx, "", "worktree", "add", dir, branch)
+}
+
+func (g *ExecGit) RemoveWorktree(ctx context.Context, dir string) error {
+	return g.git(ctx, "", "worktree", "remove", "--force", dir)
+}
+
+func (g *ExecGit) ListWorktrees(ctx context.Context) ([]Worktree, error) {
+	output, err := g.output(ctx, "", "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	var worktrees []Worktree
+	for _, block := range strings.Split(output, "\n\n") {
+		var wt Worktree
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "worktree":
+				wt.Path = value
+			case "HEAD":
+				wt.Head = value
+			case "branch":
+				wt.Branch = strings.TrimPrefix(value, "refs/heads/")
+			case "prunable":
+				wt.Prunable = true
+			}
+		}
+		if wt.Path != "" {
+			worktrees = append(worktrees, wt)
+		}
+	}
+
+	return worktrees, nil
+}
+
+func (g *ExecGit) PruneWorktrees(ctx context.Context) error {
+	return g.git(ctx, "", "worktree", "prune")
+}
+
+func (g *ExecGit) StageAll(ctx context.Context, dir string) error {
+	return g.git(ctx, dir, "add", "-A")
+}
+
+func (g *ExecGit) Commit(ctx context.Context, dir, message string) error {
+	return g.git(ctx, dir, "commit", "-m", message)
+}
+
+func (g *ExecGit) HasChanges(ctx context.Context, dir string) (bool, error) {
+	output, err := g.output(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// TrackedFiles returns the files under paths that are tracked in the main working tree.
+func (g *ExecGit) TrackedFiles(ctx context.Context, paths ...string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	output, err := g.output(ctx, "", append([]string{"ls-files", "--"}, paths...)...)
+	if err != nil || output == "" {
+		return nil, err
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// Untrack removes paths from the index of the main working tree, keeping the files.
+func (g *ExecGit) Untrack(ctx context.Context, paths ...string) error {
+	return g.git(ctx, "", append([]string{"rm", "-r", "--cached", "--quiet", "--"}, paths...)...)
+}
+
+func (g *ExecGit) RemoteBranchExists(ctx context.Context, remote, branch string) (bool, error) {
+	output, err := g.output(ctx, "", "ls-remote", "--heads", remote, "refs/heads/"+branch)
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+// Fetch updates refs/remotes/<remote>/<branch> from the remote.
+func (g *ExecGit) Fetch(ctx context.Context, remote, branch string) error {
+	refspec := fmt.Sprintf("refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch)
+	return g.git(ctx, "", "fetch", remote, refspec)
+}
+
+func (g *ExecGit) Pull(ctx context.Context, dir string) error {
+	upstream, err := g.output(ctx, dir, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return err
+	}
+	branch, err := g.output(ctx, dir, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+	remote, err := g.output(ctx, dir, "config", "branch."+branch+".remote")
+	if err != nil {
+		return err
+	}
+	if err := g.Fetch(ctx, remote, strings.TrimPrefix(upstream, remote+"/")); err != nil {
+		return err
+	}
+	return g.Rebase(ctx, dir, upstream)
+}
+
+func (g *ExecGit) Rebase(ctx context.Context, dir, onto string) error {
+	err := g.git(ctx, dir, "rebase", onto)
+	if err != nil {
+		// Leave the branch as it was rather than mid-rebase.
+		_ = g.git(ctx, dir, "rebase", "--abort")
+	}
+	return err
+}
+
+func (g *ExecGit) Push(ctx context.Context, dir, remote, branch string) error {
+	return g.git(ctx, dir, "push", remote, branch)
+}
+
+func (g *ExecGit) DeleteRemoteBranch(ctx context.Context, remote, branch string) error {
+	return g.git(ctx, "", "push", remote, "--delete", branch)
+}
+
+// GitPath resolves a path inside the repository's git directory, e.g. "info/exclude".
+func (g *ExecGit) GitPath(ctx context.Context, name string) (string, error) {
+	return g.output(ctx, "", "rev-parse", "--git-path", name)
+}
+
+func (g *ExecGit) SetConfig(ctx context.Context, key, value string) error {
+	return g.git(ctx, "", "config", key, value)
+}
+
+func (g *ExecGit) CreateBundle(ctx context.Context, file, branch string, exclude []string) error {
+	args := []string{"bundle", "create", file, "refs/heads/" + branch}
+	for _, rev := range exclude {
+		args = append(args, "^"+rev)
+	}
+	return g.git(ctx, "", args...)
+}
+
+func (g *ExecGit) BundleHeads(ctx context.Context, file string) (map[string]string, error) {
+	out, err := g.output(ctx, "", "bundle", "list-heads", file)
+	if err != nil {
+		return nil, err
+	}
+	heads := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if sha, ref, ok := strings.Cut(line, " "); ok {
+			heads[ref] = sha
+		}
+	}
+	return heads, nil
+}
+
+func (g *ExecGit) FetchBundle(ctx context.Context, file, ref, dst string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if err := g.git(ctx, "", "bundle", "verify", abs); err != nil {
+		return err
+	}
+	return g.git(ctx, "", "fetch", "--no-tags", abs, "+"+ref+":"+dst)
+}
+
+func (g *ExecGit) Clone(ctx context.Context, url, ref, dir string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	// init and fetch rather than clone, which cannot check out a commit.
+	if err := g.git(ctx, dir, "init", "--quiet"); err != nil {
+		return "", err
+	}
+	if err := g.git(ctx, dir, "fetch", "--quiet", "--depth", "1", "--no-tags", url, ref); err != nil {
+		return "", err
+	}
+	if err := g.git(ctx, dir, "checkout", "--quiet", "--detach", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	return g.output(ctx, dir, "rev-parse", "HEAD")
+}
+
+func (g *ExecGit) Archive(ctx context.Context, rev, format, output string) error {
+	err := g.git(ctx, "", "archive", "--format="+format, "-o", output, rev)
+	if err != nil {
+		// Do not leave a truncated archive behind.
+		os.Remove(output)
+	}
+	return err
+}
@@ -0,0 +1,1379 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage"
+)
+
+// GoGit implements Git in pure Go with go-git, for machines without a usable
+// git binary. Worktrees use git's own linked-worktree layout
+// (.git/worktrees/<name>), so they stay compatible with the git binary.
+type GoGit struct{}
+
+func init() {
+	// go-git's default file:// transport runs git-upload-pack and
+	// git-receive-pack; serve local remotes in-process instead.
+	client.InstallProtocol("file", &localTransport{
+		Transport: server.NewClient(server.DefaultLoader),
+		loader:    server.DefaultLoader,
+	})
+}
+
+// localTransport serves local remotes with go-git's embedded server.
+type localTransport struct {
+	transport.Transport
+	loader server.Loader
+}
+
+func (t *localTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	session, err := t.Transport.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return nil, err
+	}
+	sto, err := t.loader.Load(ep)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &knownHavesSession{UploadPackSession: session, storer: sto}, nil
+}
+
+// knownHavesSession drops the commits the remote does not have from a fetch
+// request. Unlike git-upload-pack, the embedded server fails on them, which
+// breaks every fetch made while there are unpushed local commits.
+type knownHavesSession struct {
+	transport.UploadPackSession
+	storer storer.EncodedObjectStorer
+}
+
+func (s *knownHavesSession) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	haves := req.Haves[:0]
+	for _, h := range req.Haves {
+		if s.storer.HasEncodedObject(h) == nil {
+			haves = append(haves, h)
+		}
+	}
+	req.Haves = haves
+	return s.UploadPackSession.UploadPack(ctx, req)
+}
+
+// gogitError wraps err in a *GitError, classifying it like ExecGit does.
+func gogitError(err error, args ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, gogit.ErrRepositoryNotExists):
+		kind = ErrNotRepository
+	case errors.Is(err, plumbing.ErrReferenceNotFound),
+		errors.Is(err, plumbing.ErrObjectNotFound),
+		errors.Is(err, gogit.ErrBranchNotFound),
+		errors.Is(err, gogit.ErrRemoteNotFound),
+		errors.Is(err, transport.ErrRepositoryNotFound):
+		kind = ErrNotFound
+	case errors.Is(err, gogit.ErrBranchExists),
+		errors.Is(err, storage.ErrReferenceHasChanged):
+		kind = ErrAlreadyExists
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed):
+		kind = ErrAuthFailed
+	case errors.Is(err, gogit.ErrNonFastForwardUpdate),
+		errors.Is(err, gogit.ErrFastForwardMergeNotPossible):
+		kind = ErrConflict
+	case errors.As(err, &netErr):
+		kind = ErrNetwork
+	default:
+		kind = classifyGitError(err.Error())
+	}
+
+	return &GitError{Args: args, ExitCode: -1, Kind: kind, Err: err}
+}
+
+// open opens the repository checked out at dir; an empty dir means the main
+// working tree.
+func (g *GoGit) open(dir string) (*gogit.Repository, error) {
+	if dir == "" {
+		dir = "."
+	}
+	repo, err := gogit.PlainOpenWithOptions(dir, &gogit.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, gogitError(err, "open", dir)
+	}
+	return repo, nil
+}
+
+// commonDir returns the git directory shared by all worktrees of the main
+// working tree.
+func (g *GoGit) commonDir() (string, error) {
+	gitDir, err := resolveGitDir(".")
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if os.IsNotExist(err) {
+		return gitDir, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common), nil
+}
+
+// resolveGitDir returns the git directory of the working tree at dir,
+// following a ".git" file as written for linked worktrees.
+func resolveGitDir(dir string) (string, error) {
+	dotGit := filepath.Join(dir, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", &GitError{Args: []string{"rev-parse", "--git-dir"}, ExitCode: -1, Kind: ErrNotRepository, Err: err}
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("invalid .git file in %s", dir)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+func (g *GoGit) worktree(dir string) (*gogit.Repository, *gogit.Worktree, error) {
+	repo, err := g.open(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, nil, gogitError(err, "worktree", dir)
+	}
+	return repo, w, nil
+}
+
+// auth picks credentials for remote. ssh remotes use the ssh agent, or the
+// default key files when no agent is running; http(s) remotes use the
+// credentials in the URL or AI_DOCS_GIT_USERNAME / AI_DOCS_GIT_PASSWORD.
+func (g *GoGit) auth(repo *gogit.Repository, remote string) (transport.AuthMethod, error) {
+	r, err := repo.Remote(remote)
+	if err != nil {
+		return nil, gogitError(err, "remote", remote)
+	}
+	urls := r.Config().URLs
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	return authForURL(urls[0])
+}
+
+// authForURL returns the credentials for url, if any.
+func authForURL(url string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Protocol {
+	case "http", "https":
+		user, password := os.Getenv("AI_DOCS_GIT_USERNAME"), os.Getenv("AI_DOCS_GIT_PASSWORD")
+		if ep.User == "" && password != "" {
+			if user == "" {
+				user = "git"
+			}
+			return &http.BasicAuth{Username: user, Password: password}, nil
+		}
+	case "ssh":
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			return nil, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			key := filepath.Join(home, ".ssh", name)
+			if PathExists(key) {
+				return ssh.NewPublicKeysFromFile(ep.User, key, "")
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (g *GoGit) BranchExists(ctx context.Context, branch string) bool {
+	repo, err := g.open("")
+	if err != nil {
+		return false
+	}
+	_, err = repo.Reference(plumbing.NewBranchReferenceName(branch), false)
+	return err == nil
+}
+
+func (g *GoGit) CurrentBranch(ctx context.Context) (string, error) {
+	repo, err := g.open("")
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", gogitError(err, "branch", "--show-current")
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return "", nil
+	}
+	return head.Target().Short(), nil
+}
+
+func (g *GoGit) CreateBranch(ctx context.Context, branch, startPoint string) error {
+	args := []string{"branch", branch, startPoint}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	hash, err := g.resolve(repo, startPoint)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	return g.setNewRef(repo, plumbing.NewBranchReferenceName(branch), hash, args)
+}
+
+// resolve returns the commit rev points to. Ref names are looked up directly
+// first, because go-git's revision parser rejects names such as "@doc/me".
+func (g *GoGit) resolve(repo *gogit.Repository, rev string) (plumbing.Hash, error) {
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.ReferenceName(rev),
+		plumbing.NewBranchReferenceName(rev),
+		plumbing.ReferenceName("refs/remotes/" + rev),
+		plumbing.NewTagReferenceName(rev),
+	} {
+		if ref, err := repo.Reference(name, true); err == nil {
+			return ref.Hash(), nil
+		}
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return *hash, nil
+}
+
+// setNewRef points name at hash, failing if name already exists.
+func (g *GoGit) setNewRef(repo *gogit.Repository, name plumbing.ReferenceName, hash plumbing.Hash, args []string) error {
+	if _, err := repo.Reference(name, false); err == nil {
+		return gogitError(fmt.Errorf("a branch named '%s' already exists", name.Short()), args...)
+	}
+	return gogitError(repo.Storer.SetReference(plumbing.NewHashReference(name, hash)), args...)
+}
+
+// CreateTrackingBranch fetches branch from remote and creates a local branch tracking it.
+func (g *GoGit) CreateTrackingBranch(ctx context.Context, remote, branch string) error {
+	if err := g.Fetch(ctx, remote, branch); err != nil {
+		return err
+	}
+	if err := g.CreateBranch(ctx, branch, remote+"/"+branch); err != nil {
+		return err
+	}
+	return g.SetUpstream(ctx, remote, branch)
+}
+
+// CreateOrphanBranch creates branch as a parentless commit containing the
+// given paths, read relative to dir ("" for the current directory), leaving
+// HEAD, the index and the working tree untouched. Paths that do not exist are
+// skipped. It fails if the branch already exists.
+func (g *GoGit) CreateOrphanBranch(ctx context.Context, branch, dir string, paths []string, message string) (string, error) {
+	args := []string{"commit-tree", branch}
+	repo, err := g.open("")
+	if err != nil {
+		return "", err
+	}
+
+	root, err := buildTree(dir, paths, func(file string, info os.FileInfo) (string, string, error) {
+		return g.hashObject(repo, file, info)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := g.writeTree(repo, root)
+	if err != nil {
+		return "", gogitError(err, args...)
+	}
+
+	cfg, err := repo.ConfigScoped(gitconfig.SystemScope)
+	if err != nil {
+		return "", gogitError(err, args...)
+	}
+	if cfg.User.Name == "" || cfg.User.Email == "" {
+		return "", gogitError(errors.New("user.name and user.email must be set in the git config"), args...)
+	}
+	sig := object.Signature{Name: cfg.User.Name, Email: cfg.User.Email, When: time.Now()}
+	commit := &object.Commit{
+		Author:    sig,
+		Committer: sig,
+		Message:   message + "\n",
+		TreeHash:  tree,
+	}
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return "", gogitError(err, args...)
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return "", gogitError(err, args...)
+	}
+
+	if err := g.setNewRef(repo, plumbing.NewBranchReferenceName(branch), hash, []string{"update-ref", branch}); err != nil {
+		return "", err
+	}
+	return hash.String(), nil
+}
+
+func (g *GoGit) hashObject(repo *gogit.Repository, file string, info os.FileInfo) (mode, sha string, err error) {
+	var content io.Reader
+	mode = "100644"
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(file)
+		if err != nil {
+			return "", "", err
+		}
+		mode = "120000"
+		content = strings.NewReader(target)
+	default:
+		if info.Mode()&0111 != 0 {
+			mode = "100755"
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return "", "", err
+		}
+		defer f.Close()
+		content = f
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := io.Copy(w, content); err != nil {
+		w.Close()
+		return "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", "", err
+	}
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return "", "", err
+	}
+	return mode, hash.String(), nil
+}
+
+// writeTree stores the node and its subdirectories as tree objects and returns the tree id.
+func (g *GoGit) writeTree(repo *gogit.Repository, n *treeNode) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	for name, entry := range n.entries {
+		mode, err := filemode.New(entry.mode)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: mode, Hash: plumbing.NewHash(entry.sha)})
+	}
+	for name, dir := range n.dirs {
+		hash, err := g.writeTree(repo, dir)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// git orders tree entries as if directory names ended with a slash.
+	sortKey := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return repo.Storer.SetEncodedObject(obj)
+}
+
+func (g *GoGit) DeleteBranch(ctx context.Context, branch string) error {
+	args := []string{"branch", "-D", branch}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	name := plumbing.NewBranchReferenceName(branch)
+	if _, err := repo.Reference(name, false); err != nil {
+		return gogitError(fmt.Errorf("branch '%s' not found", branch), args...)
+	}
+	if err := repo.Storer.RemoveReference(name); err != nil {
+		return gogitError(err, args...)
+	}
+	if err := repo.DeleteBranch(branch); err != nil && !errors.Is(err, gogit.ErrBranchNotFound) {
+		return gogitError(err, args...)
+	}
+	return nil
+}
+
+func (g *GoGit) ResolveRef(ctx context.Context, ref string) (string, error) {
+	repo, err := g.open("")
+	if err != nil {
+		return "", err
+	}
+	hash, err := g.resolve(repo, ref)
+	if err != nil {
+		return "", gogitError(err, "rev-parse", "--verify", ref)
+	}
+	return hash.String(), nil
+}
+
+// Upstream returns the upstream of branch, e.g. "origin/main".
+func (g *GoGit) Upstream(ctx context.Context, branch string) (string, error) {
+	args := []string{"rev-parse", "--abbrev-ref", branch + "@{upstream}"}
+	repo, err := g.open("")
+	if err != nil {
+		return "", err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", gogitError(err, args...)
+	}
+	b, ok := cfg.Branches[branch]
+	if !ok || b.Remote == "" || b.Merge == "" {
+		return "", gogitError(fmt.Errorf("no upstream configured for branch '%s'", branch), args...)
+	}
+	return b.Remote + "/" + b.Merge.Short(), nil
+}
+
+func (g *GoGit) Ahead(ctx context.Context, rev, base string) (int, error) {
+	args := []string{"rev-list", "--count", base + ".." + rev}
+	repo, err := g.open("")
+	if err != nil {
+		return 0, err
+	}
+	revHash, err := g.resolve(repo, rev)
+	if err != nil {
+		return 0, gogitError(err, args...)
+	}
+	baseHash, err := g.resolve(repo, base)
+	if err != nil {
+		return 0, gogitError(err, args...)
+	}
+
+	inBase := map[plumbing.Hash]bool{}
+	commits, err := repo.Log(&gogit.LogOptions{From: baseHash})
+	if err != nil {
+		return 0, gogitError(err, args...)
+	}
+	if err := commits.ForEach(func(c *object.Commit) error {
+		inBase[c.Hash] = true
+		return ctx.Err()
+	}); err != nil {
+		return 0, gogitError(err, args...)
+	}
+
+	count := 0
+	if commits, err = repo.Log(&gogit.LogOptions{From: revHash}); err != nil {
+		return 0, gogitError(err, args...)
+	}
+	err = commits.ForEach(func(c *object.Commit) error {
+		if !inBase[c.Hash] {
+			count++
+		}
+		return ctx.Err()
+	})
+	return count, gogitError(err, args...)
+}
+
+func (g *GoGit) SetUpstream(ctx context.Context, remote, branch string) error {
+	args := []string{"branch", "--set-upstream-to=" + remote + "/" + branch, branch}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	if _, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, branch), false); err != nil {
+		return gogitError(fmt.Errorf("the requested upstream branch '%s/%s' does not exist: %w", remote, branch, err), args...)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	cfg.Branches[branch] = &gitconfig.Branch{
+		Name:   branch,
+		Remote: remote,
+		Merge:  plumbing.NewBranchReferenceName(branch),
+	}
+	return gogitError(repo.SetConfig(cfg), args...)
+}
+
+func (g *GoGit) Switch(ctx context.Context, branch string) error {
+	_, w, err := g.worktree("")
+	if err != nil {
+		return err
+	}
+	err = w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Keep: true})
+	return gogitError(err, "switch", branch)
+}
+
+// AddWorktree checks out branch into dir as a linked worktree.
+func (g *GoGit) AddWorktree(ctx context.Context, dir, branch string) (err error) {
+	args := []string{"worktree", "add", dir, branch}
+	if !g.BranchExists(ctx, branch) {
+		return gogitError(fmt.Errorf("invalid reference: %s", branch), args...)
+	}
+
+	worktrees, err := g.ListWorktrees(ctx)
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			return gogitError(fmt.Errorf("'%s' is already checked out at '%s'", branch, wt.Path), args...)
+		}
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return gogitError(fmt.Errorf("'%s' already exists", dir), args...)
+	}
+
+	common, err := g.commonDir()
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	absCommon, err := filepath.Abs(common)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimLeft(filepath.Base(absDir), ".")
+	if name == "" {
+		name = "worktree"
+	}
+	admin := filepath.Join(absCommon, "worktrees", name)
+	for i := 1; PathExists(admin); i++ {
+		admin = filepath.Join(absCommon, "worktrees", name+strconv.Itoa(i))
+	}
+
+	createdDir := !PathExists(absDir)
+	defer func() {
+		if err != nil {
+			os.RemoveAll(admin)
+			if createdDir {
+				os.RemoveAll(absDir)
+			} else {
+				os.Remove(filepath.Join(absDir, ".git"))
+			}
+		}
+	}()
+
+	files := map[string]string{
+		filepath.Join(admin, "HEAD"):      "ref: refs/heads/" + branch + "\n",
+		filepath.Join(admin, "commondir"): "../..\n",
+		filepath.Join(admin, "gitdir"):    filepath.Join(absDir, ".git") + "\n",
+		filepath.Join(absDir, ".git"):     "gitdir: " + admin + "\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	_, w, err := g.worktree(dir)
+	if err != nil {
+		return err
+	}
+	err = w.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Force: true})
+	return gogitError(err, args...)
+}
+
+func (g *GoGit) RemoveWorktree(ctx context.Context, dir string) error {
+	args := []string{"worktree", "remove", "--force", dir}
+	gitDir, err := resolveGitDir(dir)
+	if err != nil {
+		return gogitError(fmt.Errorf("'%s' is not a working tree", dir), args...)
+	}
+	if filepath.Base(filepath.Dir(gitDir)) != "worktrees" {
+		return gogitError(fmt.Errorf("'%s' is a main working tree", dir), args...)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return gogitError(err, args...)
+	}
+	return gogitError(os.RemoveAll(gitDir), args...)
+}
+
+func (g *GoGit) ListWorktrees(ctx context.Context) ([]Worktree, error) {
+	repo, err := g.open("")
+	if err != nil {
+		return nil, err
+	}
+	common, err := g.commonDir()
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := filepath.Abs(".")
+	if err != nil {
+		return nil, err
+	}
+	main := Worktree{Path: root}
+	if head, err := repo.Storer.Reference(plumbing.HEAD); err == nil {
+		main.Head, main.Branch = g.describeHead(repo, head)
+	}
+	worktrees := []Worktree{main}
+
+	entries, err := os.ReadDir(filepath.Join(common, "worktrees"))
+	if os.IsNotExist(err) {
+		return worktrees, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		admin := filepath.Join(common, "worktrees", e.Name())
+		gitdir, err := os.ReadFile(filepath.Join(admin, "gitdir"))
+		if err != nil {
+			continue
+		}
+		dotGit := strings.TrimSpace(string(gitdir))
+		wt := Worktree{Path: filepath.Dir(dotGit), Prunable: !PathExists(dotGit)}
+
+		if data, err := os.ReadFile(filepath.Join(admin, "HEAD")); err == nil {
+			content := strings.TrimSpace(string(data))
+			var head *plumbing.Reference
+			if target, ok := strings.CutPrefix(content, "ref: "); ok {
+				head = plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.ReferenceName(target))
+			} else {
+				head = plumbing.NewHashReference(plumbing.HEAD, plumbing.NewHash(content))
+			}
+			wt.Head, wt.Branch = g.describeHead(repo, head)
+		}
+		worktrees = append(worktrees, wt)
+	}
+
+	return worktrees, nil
+}
+
+// describeHead returns the commit and branch name HEAD points to.
+func (g *GoGit) describeHead(repo *gogit.Repository, head *plumbing.Reference) (hash, branch string) {
+	if head.Type() == plumbing.HashReference {
+		return head.Hash().String(), ""
+	}
+	branch = head.Target().Short()
+	if ref, err := repo.Reference(head.Target(), true); err == nil {
+		hash = ref.Hash().String()
+	}
+	return hash, branch
+}
+
+func (g *GoGit) PruneWorktrees(ctx context.Context) error {
+	common, err := g.commonDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(common, "worktrees"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		admin := filepath.Join(common, "worktrees", e.Name())
+		gitdir, err := os.ReadFile(filepath.Join(admin, "gitdir"))
+		if err == nil && PathExists(strings.TrimSpace(string(gitdir))) {
+			continue
+		}
+		if err := os.RemoveAll(admin); err != nil {
+			return gogitError(err, "worktree", "prune")
+		}
+	}
+	return nil
+}
+
+func (g *GoGit) StageAll(ctx context.Context, dir string) error {
+	_, w, err := g.worktree(dir)
+	if err != nil {
+		return err
+	}
+	return gogitError(w.AddWithOptions(&gogit.AddOptions{All: true}), "add", "-A")
+}
+
+func (g *GoGit) Commit(ctx context.Context, dir, message string) error {
+	_, w, err := g.worktree(dir)
+	if err != nil {
+		return err
+	}
+	_, err = w.Commit(message, &gogit.CommitOptions{})
+	return gogitError(err, "commit", "-m", message)
+}
+
+func (g *GoGit) HasChanges(ctx context.Context, dir string) (bool, error) {
+	_, w, err := g.worktree(dir)
+	if err != nil {
+		return false, err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return false, gogitError(err, "status")
+	}
+	return !status.IsClean(), nil
+}
+
+// TrackedFiles returns the files under paths that are tracked in the main working tree.
+func (g *GoGit) TrackedFiles(ctx context.Context, paths ...string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	repo, err := g.open("")
+	if err != nil {
+		return nil, err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, gogitError(err, "ls-files")
+	}
+
+	var files []string
+	for _, e := range idx.Entries {
+		if indexPathMatches(e.Name, paths) {
+			files = append(files, e.Name)
+		}
+	}
+	return files, nil
+}
+
+// Untrack removes paths from the index of the main working tree, keeping the files.
+func (g *GoGit) Untrack(ctx context.Context, paths ...string) error {
+	args := append([]string{"rm", "-r", "--cached", "--"}, paths...)
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return gogitError(err, args...)
+	}
+
+	kept := idx.Entries[:0]
+	removed := 0
+	for _, e := range idx.Entries {
+		if indexPathMatches(e.Name, paths) {
+			removed++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if removed == 0 {
+		return gogitError(fmt.Errorf("pathspec '%s' did not match any files", strings.Join(paths, " ")), args...)
+	}
+	idx.Entries = kept
+	// The cached trees describe the old entries.
+	idx.Cache = nil
+	return gogitError(repo.Storer.SetIndex(idx), args...)
+}
+
+// indexPathMatches reports whether the index entry name is one of paths or
+// lies below one of them.
+func indexPathMatches(name string, paths []string) bool {
+	for _, p := range paths {
+		p = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(p)), "/")
+		if p == "." || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GoGit) RemoteBranchExists(ctx context.Context, remote, branch string) (bool, error) {
+	args := []string{"ls-remote", "--heads", remote, branch}
+	repo, err := g.open("")
+	if err != nil {
+		return false, err
+	}
+	r, err := repo.Remote(remote)
+	if err != nil {
+		return false, gogitError(err, args...)
+	}
+	auth, err := g.auth(repo, remote)
+	if err != nil {
+		return false, gogitError(err, args...)
+	}
+	refs, err := r.ListContext(ctx, &gogit.ListOptions{Auth: auth})
+	if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return false, nil
+	}
+	if err != nil {
+		return false, gogitError(err, args...)
+	}
+
+	name := plumbing.NewBranchReferenceName(branch)
+	for _, ref := range refs {
+		if ref.Name() == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Fetch updates refs/remotes/<remote>/<branch> from the remote.
+func (g *GoGit) Fetch(ctx context.Context, remote, branch string) error {
+	refspec := fmt.Sprintf("refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch)
+	args := []string{"fetch", remote, refspec}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	auth, err := g.auth(repo, remote)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	err = repo.FetchContext(ctx, &gogit.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(refspec)},
+		Auth:       auth,
+	})
+	if errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return gogitError(err, args...)
+}
+
+// Pull fetches the upstream of the branch checked out in dir and rebases
+// the branch onto it.
+func (g *GoGit) Pull(ctx context.Context, dir string) error {
+	args := []string{"pull"}
+	repo, _, err := g.worktree(dir)
+	if err != nil {
+		return err
+	}
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	b, ok := cfg.Branches[head.Target().Short()]
+	if head.Type() != plumbing.SymbolicReference || !ok || b.Remote == "" || b.Merge == "" {
+		return gogitError(errors.New("there is no tracking information for the current branch"), args...)
+	}
+
+	if err := g.Fetch(ctx, b.Remote, b.Merge.Short()); err != nil {
+		return err
+	}
+	return g.Rebase(ctx, dir, b.Remote+"/"+b.Merge.Short())
+}
+
+// Rebase replays the commits of the branch checked out in dir onto onto.
+// go-git cannot rebase, so each commit is replayed file by file: it conflicts
+// when it changes a file that onto changed differently.
+func (g *GoGit) Rebase(ctx context.Context, dir, onto string) error {
+	args := []string{"rebase", onto}
+	repo, w, err := g.worktree(dir)
+	if err != nil {
+		return err
+	}
+	// Like git, refuse to run over uncommitted changes to tracked files,
+	// which the reset at the end would discard.
+	status, err := w.Status()
+	if err != nil {
+		return gogitError(err, "status")
+	}
+	untracked := map[string]bool{}
+	for path, s := range status {
+		switch {
+		case s.Worktree == gogit.Untracked:
+			untracked[path] = true
+		case s.Staging != gogit.Unmodified || s.Worktree != gogit.Unmodified:
+			return gogitError(errors.New("cannot rebase: You have unstaged changes"), args...)
+		}
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	ontoHash, err := g.resolve(repo, onto)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	tip, err := repo.CommitObject(ontoHash)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+
+	bases, err := headCommit.MergeBase(tip)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	// Like git, replay the whole branch if it has no common history with
+	// onto.
+	var base plumbing.Hash
+	if len(bases) > 0 {
+		base = bases[0].Hash
+	}
+	if base == tip.Hash {
+		return nil
+	}
+
+	// Collect the local commits, oldest first.
+	var commits []*object.Commit
+	for c := headCommit; c.Hash != base; {
+		commits = append([]*object.Commit{c}, commits...)
+		if c.NumParents() == 0 {
+			if !base.IsZero() {
+				return gogitError(fmt.Errorf("commit %s is not based on %s", c.Hash, base), args...)
+			}
+			break
+		}
+		if c, err = c.Parent(0); err != nil {
+			return gogitError(err, args...)
+		}
+	}
+
+	files, err := treeFiles(tip)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	for _, c := range commits {
+		if err := ctx.Err(); err != nil {
+			return gogitError(err, args...)
+		}
+		before := map[string]treeEntry{}
+		if c.NumParents() > 0 {
+			parent, err := c.Parent(0)
+			if err != nil {
+				return gogitError(err, args...)
+			}
+			if before, err = treeFiles(parent); err != nil {
+				return gogitError(err, args...)
+			}
+		}
+		after, err := treeFiles(c)
+		if err != nil {
+			return gogitError(err, args...)
+		}
+
+		for _, path := range changedPaths(before, after) {
+			switch files[path] {
+			case after[path]:
+			case before[path]:
+				if entry, ok := after[path]; ok {
+					files[path] = entry
+				} else {
+					delete(files, path)
+				}
+			default:
+				return gogitError(fmt.Errorf("CONFLICT: %s was changed on both sides; could not apply %s", path, c.Hash.String()[:7]), args...)
+			}
+		}
+
+		root := newTreeNode()
+		for path, entry := range files {
+			root.add(path, entry)
+		}
+		tree, err := g.writeTree(repo, root)
+		if err != nil {
+			return gogitError(err, args...)
+		}
+
+		committer := c.Committer
+		committer.When = time.Now()
+		replayed := &object.Commit{
+			Author:       c.Author,
+			Committer:    committer,
+			Message:      c.Message,
+			TreeHash:     tree,
+			ParentHashes: []plumbing.Hash{tip.Hash},
+		}
+		obj := repo.Storer.NewEncodedObject()
+		if err := replayed.Encode(obj); err != nil {
+			return gogitError(err, args...)
+		}
+		hash, err := repo.Storer.SetEncodedObject(obj)
+		if err != nil {
+			return gogitError(err, args...)
+		}
+		if tip, err = repo.CommitObject(hash); err != nil {
+			return gogitError(err, args...)
+		}
+	}
+
+	// Move the checked out branch to the new tip and update the files that
+	// changed. A hard reset of the whole tree would also delete untracked
+	// files; like git, stop if one of them would be overwritten.
+	oldFiles, err := treeFiles(headCommit)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	newFiles, err := treeFiles(tip)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	changed := changedPaths(oldFiles, newFiles)
+	for _, path := range changed {
+		if untracked[path] {
+			return gogitError(fmt.Errorf("the untracked working tree file %s would be overwritten by the rebase", path), args...)
+		}
+	}
+	if len(changed) == 0 {
+		return gogitError(w.Reset(&gogit.ResetOptions{Commit: tip.Hash, Mode: gogit.SoftReset}), args...)
+	}
+	return gogitError(w.Reset(&gogit.ResetOptions{Commit: tip.Hash, Mode: gogit.HardReset, Files: changed}), args...)
+}
+
+// treeFiles returns the blobs in the tree of c by path.
+func treeFiles(c *object.Commit) (map[string]treeEntry, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]treeEntry{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		path, entry, err := walker.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.Mode == filemode.Dir || entry.Mode == filemode.Submodule {
+			continue
+		}
+		files[path] = treeEntry{mode: entry.Mode.String(), sha: entry.Hash.String()}
+	}
+}
+
+// changedPaths returns the paths whose entry differs between before and after.
+func changedPaths(before, after map[string]treeEntry) []string {
+	var paths []string
+	for path, entry := range before {
+		if after[path] != entry {
+			paths = append(paths, path)
+		}
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (g *GoGit) Push(ctx context.Context, dir, remote, branch string) error {
+	ref := plumbing.NewBranchReferenceName(branch)
+	return g.push(ctx, dir, remote, gitconfig.RefSpec(ref+":"+ref), []string{"push", remote, branch})
+}
+
+func (g *GoGit) DeleteRemoteBranch(ctx context.Context, remote, branch string) error {
+	ref := plumbing.NewBranchReferenceName(branch)
+	return g.push(ctx, "", remote, gitconfig.RefSpec(":"+ref), []string{"push", remote, "--delete", branch})
+}
+
+func (g *GoGit) push(ctx context.Context, dir, remote string, refspec gitconfig.RefSpec, args []string) error {
+	repo, err := g.open(dir)
+	if err != nil {
+		return err
+	}
+	auth, err := g.auth(repo, remote)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	err = repo.PushContext(ctx, &gogit.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   []gitconfig.RefSpec{refspec},
+		Auth:       auth,
+	})
+	if errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return gogitError(err, args...)
+}
+
+// gitPathShared lists the entries of the git directory that are shared by
+// all worktrees, as in `git rev-parse --git-path`.
+var gitPathShared = map[string]bool{
+	"config": true, "hooks": true, "info": true, "logs": true, "objects": true,
+	"packed-refs": true, "refs": true, "remotes": true, "shallow": true, "worktrees": true,
+}
+
+// GitPath resolves a path inside the repository's git directory, e.g. "info/exclude".
+func (g *GoGit) GitPath(ctx context.Context, name string) (string, error) {
+	first, _, _ := strings.Cut(filepath.ToSlash(name), "/")
+	if gitPathShared[first] && name != "logs/HEAD" {
+		common, err := g.commonDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(common, name), nil
+	}
+	gitDir, err := resolveGitDir(".")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, name), nil
+}
+
+func (g *GoGit) SetConfig(ctx context.Context, key, value string) error {
+	args := []string{"config", key, value}
+	first, last := strings.Index(key, "."), strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return gogitError(fmt.Errorf("key does not contain a section: %s", key), args...)
+	}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	section := cfg.Raw.Section(key[:first])
+	if first == last {
+		section.SetOption(key[last+1:], value)
+	} else {
+		section.Subsection(key[first+1:last]).SetOption(key[last+1:], value)
+	}
+	if err := repo.SetConfig(cfg); err != nil {
+		return gogitError(err, args...)
+	}
+	return nil
+}
+
+// Clone checks out ref from url. Branches and tags are cloned shallowly; a
+// commit needs the full history since go-git cannot fetch a single commit.
+func (g *GoGit) Clone(ctx context.Context, url, ref, dir string) (string, error) {
+	args := []string{"clone", url, dir}
+	auth, err := authForURL(url)
+	if err != nil {
+		return "", gogitError(err, args...)
+	}
+	depth := 1
+	if ep, err := transport.NewEndpoint(url); err == nil && ep.Protocol == "file" {
+		// The embedded server cannot serve shallow clones, and only loads
+		// the git directory of a non-bare repository.
+		depth = 0
+		if dotGit := filepath.Join(ep.Path, ".git"); PathExists(dotGit) {
+			url = dotGit
+		}
+	}
+	clone := func(opts *gogit.CloneOptions) (*gogit.Repository, error) {
+		opts.URL, opts.Auth, opts.Tags = url, auth, gogit.NoTags
+		if opts.Depth > 0 {
+			opts.Depth = depth
+		}
+		repo, err := gogit.PlainCloneContext(ctx, dir, false, opts)
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+		return repo, err
+	}
+
+	var repo *gogit.Repository
+	switch {
+	case ref == "":
+		repo, err = clone(&gogit.CloneOptions{Depth: 1, SingleBranch: true})
+	case plumbing.IsHash(ref):
+		if repo, err = clone(&gogit.CloneOptions{NoCheckout: true}); err == nil {
+			var w *gogit.Worktree
+			if w, err = repo.Worktree(); err == nil {
+				err = w.Checkout(&gogit.CheckoutOptions{Hash: plumbing.NewHash(ref), Force: true})
+			}
+		}
+	default:
+		repo, err = clone(&gogit.CloneOptions{ReferenceName: plumbing.NewBranchReferenceName(ref), Depth: 1, SingleBranch: true})
+		if err != nil && ctx.Err() == nil {
+			repo, err = clone(&gogit.CloneOptions{ReferenceName: plumbing.NewTagReferenceName(ref), Depth: 1, SingleBranch: true})
+		}
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", gogitError(err, args...)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", gogitError(err, args...)
+	}
+	return head.Hash().String(), nil
+}
+
+// Archive writes the tree of rev to output as a zip, tar or tar.gz file.
+func (g *GoGit) Archive(ctx context.Context, rev, format, output string) (err error) {
+	args := []string{"archive", "--format=" + format, "-o", output, rev}
+	repo, err := g.open("")
+	if err != nil {
+		return err
+	}
+	hash, err := g.resolve(repo, rev)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return gogitError(err, args...)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return gogitError(err, args...)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+
+	switch format {
+	case "zip":
+		zw := zip.NewWriter(f)
+		if err := writeZip(ctx, zw, tree, commit.Committer.When); err != nil {
+			return gogitError(err, args...)
+		}
+		return zw.Close()
+	case "tar":
+		tw := tar.NewWriter(f)
+		if err := writeTar(ctx, tw, tree, commit.Committer.When); err != nil {
+			return gogitError(err, args...)
+		}
+		return tw.Close()
+	case "tar.gz", "tgz":
+		gz := gzip.NewWriter(f)
+		tw := tar.NewWriter(gz)
+		if err := writeTar(ctx, tw, tree, commit.Committer.When); err != nil {
+			return gogitError(err, args...)
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	default:
+		return gogitError(fmt.Errorf("unknown archive format '%s'", format), args...)
+	}
+}
+
+func writeTar(ctx context.Context, tw *tar.Writer, tree *object.Tree, modTime time.Time) error {
+	return tree.Files().ForEach(func(file *object.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		mode, err := file.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{Name: file.Name, Mode: int64(mode.Perm()), ModTime: modTime}
+		if file.Mode == filemode.Symlink {
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = content
+			return tw.WriteHeader(hdr)
+		}
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = io.WriteString(tw, content)
+		return err
+	})
+}
+
+func writeZip(ctx context.Context, zw *zip.Writer, tree *object.Tree, modTime time.Time) error {
+	return tree.Files().ForEach(func(file *object.File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		mode, err := file.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+
+		hdr := &zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: modTime}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, content)
+		return err
+	})
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestGitBackendParity runs the same sequence of operations with ExecGit and
+// GoGit on fresh repositories and compares what each backend reports.
+func TestGitBackendParity(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git binary not found")
+	}
+
+	backends := []struct {
+		name string
+		git  Git
+	}{
+		{"exec", &ExecGit{}},
+		{"go-git", &GoGit{}},
+	}
+	results := make([][]string, len(backends))
+	for i, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			results[i] = gitScenario(t, b.git)
+		})
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Errorf("backends disagree:\nexec:   %q\ngo-git: %q", results[0], results[1])
+	}
+}
+
+// gitScenario sets up an origin and a working repository in a temporary
+// directory, runs g against them and returns a transcript of the results.
+func gitScenario(t *testing.T, g Git) []string {
+	ctx := context.Background()
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	work := filepath.Join(root, "work")
+	other := filepath.Join(root, "other")
+
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", origin)
+	runGit(t, root, "init", "-q", "-b", "main", work)
+	setIdentity(t, work)
+	writeTestFile(t, filepath.Join(work, "README.md"), "# proj\n")
+	runGit(t, work, "add", ".")
+	runGit(t, work, "commit", "-qm", "init")
+	runGit(t, work, "remote", "add", "origin", origin)
+	runGit(t, work, "push", "-q", "origin", "main")
+	t.Chdir(work)
+
+	var log []string
+	record := func(format string, args ...any) {
+		log = append(log, fmt.Sprintf(format, args...))
+	}
+	must := func(what string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", what, err)
+		}
+	}
+
+	branch, err := g.CurrentBranch(ctx)
+	must("CurrentBranch", err)
+	record("current %s", branch)
+
+	record("exists before %v", g.BranchExists(ctx, "doc"))
+	must("CreateBranch", g.CreateBranch(ctx, "doc", "main"))
+	record("exists after %v", g.BranchExists(ctx, "doc"))
+	if _, err := g.ResolveRef(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolveRef(missing) = %v, want ErrNotFound", err)
+	}
+
+	must("AddWorktree", g.AddWorktree(ctx, ".mem", "doc"))
+	worktrees, err := g.ListWorktrees(ctx)
+	must("ListWorktrees", err)
+	for _, wt := range worktrees {
+		record("worktree %s %s", filepath.Base(wt.Path), wt.Branch)
+	}
+
+	writeTestFile(t, filepath.Join(work, ".mem", "a.md"), "a\n")
+	changed, err := g.HasChanges(ctx, ".mem")
+	must("HasChanges", err)
+	record("changes %v", changed)
+	must("StageAll", g.StageAll(ctx, ".mem"))
+	must("Commit", g.Commit(ctx, ".mem", "add a"))
+	changed, err = g.HasChanges(ctx, ".mem")
+	must("HasChanges", err)
+	record("changes after commit %v", changed)
+
+	if _, err := g.Upstream(ctx, "doc"); !errors.Is(err, ErrNoUpstream) {
+		t.Errorf("Upstream without one = %v, want ErrNoUpstream", err)
+	}
+	must("Push", g.Push(ctx, ".mem", "origin", "doc"))
+	exists, err := g.RemoteBranchExists(ctx, "origin", "doc")
+	must("RemoteBranchExists", err)
+	record("remote exists %v", exists)
+	must("Fetch", g.Fetch(ctx, "origin", "doc"))
+	must("SetUpstream", g.SetUpstream(ctx, "origin", "doc"))
+	upstream, err := g.Upstream(ctx, "doc")
+	must("Upstream", err)
+	record("upstream %s", upstream)
+	ahead, err := g.Ahead(ctx, "doc", "main")
+	must("Ahead", err)
+	record("ahead of main %d", ahead)
+
+	// Diverge: another clone pushes b.md while the worktree commits c.md.
+	runGit(t, root, "clone", "-q", "-b", "doc", origin, other)
+	setIdentity(t, other)
+	writeTestFile(t, filepath.Join(other, "b.md"), "b\n")
+	runGit(t, other, "add", ".")
+	runGit(t, other, "commit", "-qm", "add b")
+	runGit(t, other, "push", "-q", "origin", "doc")
+	writeTestFile(t, filepath.Join(work, ".mem", "c.md"), "c\n")
+	must("StageAll", g.StageAll(ctx, ".mem"))
+	must("Commit", g.Commit(ctx, ".mem", "add c"))
+
+	must("Pull", g.Pull(ctx, ".mem"))
+	record("pulled b.md %v", PathExists(filepath.Join(work, ".mem", "b.md")))
+	ahead, err = g.Ahead(ctx, "doc", "origin/doc")
+	must("Ahead", err)
+	behind, err := g.Ahead(ctx, "origin/doc", "doc")
+	must("Ahead", err)
+	record("after pull ahead %d behind %d", ahead, behind)
+	must("Push", g.Push(ctx, ".mem", "origin", "doc"))
+
+	// Uncommitted changes: the pull must refuse to run instead of
+	// discarding them. Untracked files do not count.
+	runGit(t, other, "pull", "-q", "--rebase")
+	writeTestFile(t, filepath.Join(other, "d.md"), "d\n")
+	runGit(t, other, "add", ".")
+	runGit(t, other, "commit", "-qm", "add d")
+	runGit(t, other, "push", "-q", "origin", "doc")
+	writeTestFile(t, filepath.Join(work, ".mem", "a.md"), "uncommitted\n")
+	err = g.Pull(ctx, ".mem")
+	record("dirty pull refused %v", errors.Is(err, ErrConflict))
+	record("dirty kept %q", readTestFile(t, filepath.Join(work, ".mem", "a.md")))
+	record("dirty pulled d.md %v", PathExists(filepath.Join(work, ".mem", "d.md")))
+	runGit(t, filepath.Join(work, ".mem"), "checkout", "--", "a.md")
+	writeTestFile(t, filepath.Join(work, ".mem", "untracked.md"), "u\n")
+	must("Pull with an untracked file", g.Pull(ctx, ".mem"))
+	record("untracked kept %v", PathExists(filepath.Join(work, ".mem", "untracked.md")))
+	must("Remove", os.Remove(filepath.Join(work, ".mem", "untracked.md")))
+
+	// An untracked file the pull would overwrite stops it.
+	writeTestFile(t, filepath.Join(other, "e.md"), "theirs\n")
+	runGit(t, other, "add", ".")
+	runGit(t, other, "commit", "-qm", "add e")
+	runGit(t, other, "push", "-q", "origin", "doc")
+	writeTestFile(t, filepath.Join(work, ".mem", "e.md"), "mine\n")
+	err = g.Pull(ctx, ".mem")
+	record("overwriting pull refused %v", errors.Is(err, ErrConflict))
+	record("untracked e.md kept %q", readTestFile(t, filepath.Join(work, ".mem", "e.md")))
+	must("Remove", os.Remove(filepath.Join(work, ".mem", "e.md")))
+
+	// Conflict: both sides change a.md.
+	runGit(t, other, "pull", "-q", "--rebase")
+	writeTestFile(t, filepath.Join(other, "a.md"), "theirs\n")
+	runGit(t, other, "commit", "-qam", "theirs")
+	runGit(t, other, "push", "-q", "origin", "doc")
+	writeTestFile(t, filepath.Join(work, ".mem", "a.md"), "mine\n")
+	must("StageAll", g.StageAll(ctx, ".mem"))
+	must("Commit", g.Commit(ctx, ".mem", "mine"))
+
+	err = g.Pull(ctx, ".mem")
+	record("conflict %v", errors.Is(err, ErrConflict))
+	record("kept %q", readTestFile(t, filepath.Join(work, ".mem", "a.md")))
+	changed, err = g.HasChanges(ctx, ".mem")
+	must("HasChanges", err)
+	record("changes after conflict %v", changed)
+
+	must("RemoveWorktree", g.RemoveWorktree(ctx, ".mem"))
+	record("worktree removed %v", !PathExists(filepath.Join(work, ".mem")))
+	tracked, err := g.TrackedFiles(ctx, "README.md", "missing.md")
+	must("TrackedFiles", err)
+	record("tracked %v", tracked)
+
+	return log
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, output)
+	}
+}
+
+func setIdentity(t *testing.T, dir string) {
+	t.Helper()
+	runGit(t, dir, "config", "user.name", "tester")
+	runGit(t, dir, "config", "user.email", "tester@example.com")
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// pushGit is a Git whose Push, Fetch and Rebase return queued errors and
+// record the calls. Other methods are not used by PushWithRetry.
+type pushGit struct {
+	Git
+	pushes []error
+	fetch  error
+	rebase error
+	calls  []string
+}
+
+func (g *pushGit) Push(ctx context.Context, dir, remote, branch string) error {
+	g.calls = append(g.calls, "push")
+	err := g.pushes[0]
+	g.pushes = g.pushes[1:]
+	return err
+}
+
+func (g *pushGit) Fetch(ctx context.Context, remote, branch string) error {
+	g.calls = append(g.calls, "fetch "+remote+"/"+branch)
+	return g.fetch
+}
+
+func (g *pushGit) Rebase(ctx context.Context, dir, onto string) error {
+	g.calls = append(g.calls, "rebase "+onto)
+	return g.rebase
+}
+
+func kindErr(kind error) error {
+	return &GitError{Args: []string{"push"}, Kind: kind, Err: errors.New("exit status 1")}
+}
+
+func TestPushWithRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		git     *pushGit
+		opts    PushOptions
+		calls   []string
+		wantErr error
+	}{
+		{
+			name:  "first push succeeds",
+			git:   &pushGit{pushes: []error{nil}},
+			opts:  PushOptions{Retries: 3},
+			calls: []string{"push"},
+		},
+		{
+			name:  "network error is retried",
+			git:   &pushGit{pushes: []error{kindErr(ErrNetwork), nil}},
+			opts:  PushOptions{Retries: 3},
+			calls: []string{"push", "push"},
+		},
+		{
+			name:    "retries run out",
+			git:     &pushGit{pushes: []error{kindErr(ErrNetwork), kindErr(ErrNetwork)}},
+			opts:    PushOptions{Retries: 1},
+			calls:   []string{"push", "push"},
+			wantErr: ErrNetwork,
+		},
+		{
+			name:    "auth failure is not retried",
+			git:     &pushGit{pushes: []error{kindErr(ErrAuthFailed)}},
+			opts:    PushOptions{Retries: 3},
+			calls:   []string{"push"},
+			wantErr: ErrAuthFailed,
+		},
+		{
+			name:    "non-fast-forward without rebase",
+			git:     &pushGit{pushes: []error{kindErr(ErrNonFastForward)}},
+			opts:    PushOptions{Retries: 3},
+			calls:   []string{"push"},
+			wantErr: ErrNonFastForward,
+		},
+		{
+			name:  "non-fast-forward rebases and pushes again",
+			git:   &pushGit{pushes: []error{kindErr(ErrNonFastForward), nil}},
+			opts:  PushOptions{Retries: 3, Rebase: true},
+			calls: []string{"push", "fetch origin/doc", "rebase origin/doc", "push"},
+		},
+		{
+			name:    "rebase conflict stops",
+			git:     &pushGit{pushes: []error{kindErr(ErrNonFastForward)}, rebase: kindErr(ErrConflict)},
+			opts:    PushOptions{Retries: 3, Rebase: true},
+			calls:   []string{"push", "fetch origin/doc", "rebase origin/doc"},
+			wantErr: ErrConflict,
+		},
+		{
+			name:    "fetch failure stops",
+			git:     &pushGit{pushes: []error{kindErr(ErrNonFastForward)}, fetch: kindErr(ErrAuthFailed)},
+			opts:    PushOptions{Retries: 3, Rebase: true},
+			calls:   []string{"push", "fetch origin/doc"},
+			wantErr: ErrAuthFailed,
+		},
+		{
+			name:    "timeout",
+			git:     &pushGit{pushes: []error{kindErr(ErrNetwork)}},
+			opts:    PushOptions{Retries: 3, Timeout: time.Millisecond},
+			calls:   []string{"push"},
+			wantErr: ErrNetwork,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PushWithRetry(context.Background(), tt.git, ".mem", "doc", tt.opts)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(tt.git.calls, tt.calls) {
+				t.Errorf("calls = %q, want %q", tt.git.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestPushBackoff(t *testing.T) {
+	for attempt := 0; attempt < 40; attempt++ {
+		d := pushBackoff(attempt)
+		limit := pushBackoffMax
+		if attempt < 16 {
+			limit = min(pushBackoffBase<<attempt, pushBackoffMax)
+		}
+		if d < limit/2 || d > limit {
+			t.Errorf("pushBackoff(%d) = %s, want between %s and %s", attempt, d, limit/2, limit)
+		}
+	}
+}
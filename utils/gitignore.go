@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ManagedBlockStart = "# >>> ai-docs"
+	ManagedBlockEnd   = "# <<< ai-docs"
+)
+
+// WriteManagedBlock replaces the ai-docs block in an ignore file with the given
+// entries, appending the block if the file has none. Lines outside the block
+// are left as they are. It reports whether the file changed.
+func WriteManagedBlock(path string, entries []string) (bool, error) {
+	original, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	before, _, after, found, err := splitManagedBlock(string(original))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if !found {
+		for len(before) > 0 && strings.TrimSpace(before[len(before)-1]) == "" {
+			before = before[:len(before)-1]
+		}
+		if len(before) > 0 {
+			before = append(before, "")
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range before {
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(ManagedBlockStart + "\n")
+	b.WriteString("# Managed by ai-docs; changes inside this block are overwritten.\n")
+	for _, e := range entries {
+		b.WriteString(e + "\n")
+	}
+	b.WriteString(ManagedBlockEnd + "\n")
+	for _, line := range after {
+		b.WriteString(line + "\n")
+	}
+
+	updated := b.String()
+	if updated == string(original) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// RemoveManagedBlock deletes the ai-docs block from an ignore file. It reports
+// whether a block was found and removed.
+func RemoveManagedBlock(path string) (bool, error) {
+	original, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	before, _, after, found, err := splitManagedBlock(string(original))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if !found {
+		return false, nil
+	}
+	outside := append(append([]string(nil), before...), after...)
+	for len(outside) > 0 && strings.TrimSpace(outside[len(outside)-1]) == "" {
+		outside = outside[:len(outside)-1]
+	}
+
+	content := ""
+	if len(outside) > 0 {
+		content = strings.Join(outside, "\n") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// ReadManagedBlock returns the entries inside the ai-docs block of an ignore file.
+func ReadManagedBlock(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	_, block, _, _, err := splitManagedBlock(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var entries []string
+	for _, line := range block {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		entries = append(entries, trimmed)
+	}
+	return entries, nil
+}
+
+// splitManagedBlock separates the lines of an ignore file into those before,
+// inside and after the ai-docs block. A start marker without an end marker
+// is an error, so that the rest of the file is never taken for the block.
+func splitManagedBlock(content string) (before, block, after []string, found bool, err error) {
+	if content == "" {
+		return nil, nil, nil, false, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	start, end := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if start < 0 && trimmed == ManagedBlockStart {
+			start = i
+		} else if start >= 0 && trimmed == ManagedBlockEnd {
+			end = i
+			break
+		}
+	}
+	if start < 0 {
+		return lines, nil, nil, false, nil
+	}
+	if end < 0 {
+		return nil, nil, nil, false, fmt.Errorf("%q on line %d has no matching %q - fix or remove it by hand", ManagedBlockStart, start+1, ManagedBlockEnd)
+	}
+	return lines[:start], lines[start+1 : end], lines[end+1:], true, nil
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testBlock = ManagedBlockStart + "\n# Managed by ai-docs; changes inside this block are overwritten.\nCLAUDE.md\n.mem/\n" + ManagedBlockEnd + "\n"
+
+func TestWriteManagedBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		changed bool
+	}{
+		{
+			name:    "new file",
+			want:    testBlock,
+			changed: true,
+		},
+		{
+			name:    "appends after user lines",
+			content: "node_modules/\n\n\n",
+			want:    "node_modules/\n\n" + testBlock,
+			changed: true,
+		},
+		{
+			name:    "keeps user duplicates",
+			content: "CLAUDE.md\n",
+			want:    "CLAUDE.md\n\n" + testBlock,
+			changed: true,
+		},
+		{
+			name:    "replaces block in place",
+			content: "a\n" + ManagedBlockStart + "\nold\n" + ManagedBlockEnd + "\nb\n",
+			want:    "a\n" + testBlock + "b\n",
+			changed: true,
+		},
+		{
+			name:    "unchanged",
+			content: "a\n\n" + testBlock,
+			want:    "a\n\n" + testBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gitignore")
+			if tt.content != "" {
+				writeTestFile(t, path, tt.content)
+			}
+			changed, err := WriteManagedBlock(path, []string{"CLAUDE.md", ".mem/"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if got := readTestFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveManagedBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		removed bool
+	}{
+		{
+			name:    "no block",
+			content: "a\n",
+			want:    "a\n",
+		},
+		{
+			name:    "block at end",
+			content: "CLAUDE.md\n\n" + testBlock,
+			want:    "CLAUDE.md\n",
+			removed: true,
+		},
+		{
+			name:    "block in the middle",
+			content: "a\n" + testBlock + "b\n",
+			want:    "a\nb\n",
+			removed: true,
+		},
+		{
+			name:    "only block",
+			content: testBlock,
+			want:    "",
+			removed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".gitignore")
+			writeTestFile(t, path, tt.content)
+			removed, err := RemoveManagedBlock(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != tt.removed {
+				t.Errorf("removed = %v, want %v", removed, tt.removed)
+			}
+			if got := readTestFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnterminatedManagedBlock(t *testing.T) {
+	content := "a\n" + ManagedBlockStart + "\nCLAUDE.md\nuser-rule\n"
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	writeTestFile(t, path, content)
+
+	if _, err := WriteManagedBlock(path, []string{"CLAUDE.md"}); err == nil || !strings.Contains(err.Error(), "no matching") {
+		t.Errorf("WriteManagedBlock error = %v, want an unterminated block error", err)
+	}
+	if _, err := RemoveManagedBlock(path); err == nil {
+		t.Error("RemoveManagedBlock succeeded, want an error")
+	}
+	if _, err := ReadManagedBlock(path); err == nil {
+		t.Error("ReadManagedBlock succeeded, want an error")
+	}
+	if got := readTestFile(t, path); got != content {
+		t.Errorf("file was modified:\n%s", got)
+	}
+}
+
+func TestReadManagedBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	writeTestFile(t, path, "outside\n"+testBlock)
+	got, err := ReadManagedBlock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "CLAUDE.md,.mem/" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
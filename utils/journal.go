@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// JournalEntry records a completed step and the data needed to undo it.
+type JournalEntry struct {
+	Step string            `json:"step"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Journal is a persistent log of the steps completed by a multi-step command,
+// used to roll back or resume it after a failure or interruption.
+type Journal struct {
+	path string
+	// Params are the choices made before the first step, which a resumed
+	// run must reuse rather than make again.
+	Params  map[string]string `json:"params,omitempty"`
+	Entries []JournalEntry    `json:"entries"`
+}
+
+// LoadJournal reads the journal at path. A missing file yields an empty journal.
+func LoadJournal(path string) (*Journal, error) {
+	j := &Journal{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %s: %w", path, err)
+	}
+
+	return j, nil
+}
+
+// Exists reports whether the journal has been written to disk.
+func (j *Journal) Exists() bool {
+	return PathExists(j.path)
+}
+
+// Done reports whether step has been recorded as completed.
+func (j *Journal) Done(step string) bool {
+	for _, e := range j.Entries {
+		if e.Step == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Start records params for a new run and saves the journal.
+func (j *Journal) Start(params map[string]string) error {
+	j.Params = params
+	return j.save()
+}
+
+// Record marks step as completed and saves the journal immediately, so the
+// record survives if the process is interrupted afterwards.
+func (j *Journal) Record(step string, data map[string]string) error {
+	j.Entries = append(j.Entries, JournalEntry{Step: step, Data: data})
+	return j.save()
+}
+
+// Remove deletes the journal file.
+func (j *Journal) Remove() error {
+	j.Params, j.Entries = nil, nil
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove journal %s: %w", j.path, err)
+	}
+	return nil
+}
+
+func (j *Journal) save() error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated journal.
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return os.Rename(tmp, j.path)
+}
@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeNode is an in-memory directory used to assemble tree objects.
+type treeNode struct {
+	entries map[string]treeEntry
+	dirs    map[string]*treeNode
+}
+
+// treeEntry is a blob in a treeNode.
+type treeEntry struct {
+	mode string
+	sha  string
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{entries: map[string]treeEntry{}, dirs: map[string]*treeNode{}}
+}
+
+// buildTree stores every file under the given paths, relative to dir, with
+// hash and returns the resulting directory structure. Paths that do not exist
+// are skipped.
+func buildTree(dir string, paths []string, hash func(file string, info os.FileInfo) (mode, sha string, err error)) (*treeNode, error) {
+	root := newTreeNode()
+
+	for _, p := range paths {
+		if _, err := os.Lstat(filepath.Join(dir, p)); err != nil {
+			continue
+		}
+		err := filepath.Walk(filepath.Join(dir, p), func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			mode, sha, err := hash(file, info)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, file)
+			if err != nil {
+				return err
+			}
+			root.add(filepath.ToSlash(rel), treeEntry{mode: mode, sha: sha})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", p, err)
+		}
+	}
+
+	return root, nil
+}
+
+// CreateOrphanBranch creates branch as a parentless commit containing the
+// given paths, read relative to dir ("" for the current directory). The
+// commit is built with plumbing commands (hash-object, mktree, commit-tree,
+// update-ref), so HEAD, the index and the working tree are left untouched.
+// Paths that do not exist are skipped. It fails if the branch already exists.
+func (g *ExecGit) CreateOrphanBranch(ctx context.Context, branch, dir string, paths []string, message string) (string, error) {
+	root, err := buildTree(dir, paths, func(file string, info os.FileInfo) (string, string, error) {
+		return g.hashObject(ctx, file, info)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	tree, err := g.writeTree(ctx, root)
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := g.output(ctx, "", "commit-tree", tree, "-m", message)
+	if err != nil {
+		return "", err
+	}
+
+	// An empty old value makes update-ref fail if the branch already exists.
+	if err := g.git(ctx, "", "update-ref", "-m", message, "refs/heads/"+branch, commit, ""); err != nil {
+		return "", err
+	}
+
+	return commit, nil
+}
+
+func (n *treeNode) add(path string, entry treeEntry) {
+	parts := strings.Split(path, "/")
+	node := n
+	for _, dir := range parts[:len(parts)-1] {
+		child, ok := node.dirs[dir]
+		if !ok {
+			child = newTreeNode()
+			node.dirs[dir] = child
+		}
+		node = child
+	}
+	node.entries[parts[len(parts)-1]] = entry
+}
+
+// writeTree stores the node and its subdirectories as tree objects and returns the tree id.
+func (g *ExecGit) writeTree(ctx context.Context, n *treeNode) (string, error) {
+	var lines []string
+	for name, entry := range n.entries {
+		lines = append(lines, fmt.Sprintf("%s blob %s\t%s", entry.mode, entry.sha, name))
+	}
+	for name, dir := range n.dirs {
+		sha, err := g.writeTree(ctx, dir)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, "040000 tree "+sha+"\t"+name)
+	}
+	sort.Strings(lines)
+
+	input := ""
+	if len(lines) > 0 {
+		input = strings.Join(lines, "\n") + "\n"
+	}
+	return g.run(ctx, "", strings.NewReader(input), "mktree")
+}
+
+func (g *ExecGit) hashObject(ctx context.Context, file string, info os.FileInfo) (mode, sha string, err error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(file)
+		if err != nil {
+			return "", "", err
+		}
+		sha, err = g.run(ctx, "", strings.NewReader(target), "hash-object", "-w", "--stdin")
+		return "120000", sha, err
+	}
+
+	mode = "100644"
+	if info.Mode()&0111 != 0 {
+		mode = "100755"
+	}
+	sha, err = g.output(ctx, "", "hash-object", "-w", "--", file)
+	return mode, sha, err
+}